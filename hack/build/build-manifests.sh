@@ -25,6 +25,7 @@ echo "VERBOSITY=${VERBOSITY}"
 echo "PULL_POLICY=${PULL_POLICY}"
 echo "WASP_NAMESPACE=${WASP_NAMESPACE}"
 echo "DEPLOY_PROMETHEUS_RULE=${DEPLOY_PROMETHEUS_RULE}"
+echo "DEPLOY_SERVICE_MONITOR=${DEPLOY_SERVICE_MONITOR}"
 
 source "${script_dir}"/resource-generator.sh
 
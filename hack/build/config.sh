@@ -19,6 +19,7 @@ VERBOSITY=${VERBOSITY:-1}
 PULL_POLICY=${PULL_POLICY:-Always}
 WASP_NAMESPACE=${WASP_NAMESPACE:-wasp}
 DEPLOY_PROMETHEUS_RULE=${DEPLOY_PROMETHEUS_RULE:-false}
+DEPLOY_SERVICE_MONITOR=${DEPLOY_SERVICE_MONITOR:-false}
 CR_NAME=${CR_NAME:-wasp}
 
 function parseTestOpts() {
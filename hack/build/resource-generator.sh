@@ -44,7 +44,8 @@ function generateResourceManifest() {
             -verbosity="${VERBOSITY}" \
             -pull-policy="${PULL_POLICY}" \
             -namespace="${WASP_NAMESPACE}" \
-            -deploy-prometheus-rule="${DEPLOY_PROMETHEUS_RULE}"
+            -deploy-prometheus-rule="${DEPLOY_PROMETHEUS_RULE}" \
+            -deploy-service-monitor="${DEPLOY_SERVICE_MONITOR}"
     ) 1>>"${targetDir}/"$manifestName
     (
         ${generator} -resource-type=${resourceType} \
@@ -57,7 +58,8 @@ function generateResourceManifest() {
             -verbosity="${VERBOSITY}" \
             -pull-policy="{{ pull_policy }}" \
             -namespace="{{ wasp_namespace }}" \
-            -deploy-prometheus-rule="${DEPLOY_PROMETHEUS_RULE}"
+            -deploy-prometheus-rule="${DEPLOY_PROMETHEUS_RULE}" \
+            -deploy-service-monitor="${DEPLOY_SERVICE_MONITOR}"
     ) 1>>"${targetDir}/"$manifestNamej2
 
     # Remove empty lines at the end of files which are added by go templating
@@ -117,6 +119,7 @@ function populateResourceManifest() {
             -cr-name="${CR_NAME}" \
             -namespace="${WASP_NAMESPACE}" \
             -deploy-prometheus-rule="${DEPLOY_PROMETHEUS_RULE}" \
+            -deploy-service-monitor="${DEPLOY_SERVICE_MONITOR}" \
             -generated-manifests-path=${generatedManifests}
     ) 1>>"${targetDir}/"$outfile
 
@@ -127,6 +130,7 @@ function populateResourceManifest() {
             -operator-version="{{ operator_version }}" \
             -deploy-cluster-resources="true" \
             -deploy-prometheus-rule="{{ DEPLOY_PROMETHEUS_RULE }}" \
+            -deploy-service-monitor="{{ DEPLOY_SERVICE_MONITOR }}" \
             -operator-image="{{ operator_image_name }}" \
             -verbosity="${VERBOSITY}" \
             -pull-policy="{{ pull_policy }}" \
@@ -0,0 +1,34 @@
+#!/usr/bin/env bash
+
+#Copyright 2023 The WASP Authors.
+#
+#Licensed under the Apache License, Version 2.0 (the "License");
+#you may not use this file except in compliance with the License.
+#You may obtain a copy of the License at
+#
+#    http://www.apache.org/licenses/LICENSE-2.0
+#
+#Unless required by applicable law or agreed to in writing, software
+#distributed under the License is distributed on an "AS IS" BASIS,
+#WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+#See the License for the specific language governing permissions and
+#limitations under the License.
+
+set -o errexit
+set -o nounset
+set -o pipefail
+
+SCRIPT_ROOT="$(cd "$(dirname "${BASH_SOURCE[0]}")/.." && pwd -P)"
+cd "${SCRIPT_ROOT}"
+
+MODULE=github.com/openshift-virtualization/wasp-agent
+OUTPUT_BASE="$(mktemp -d)"
+trap 'rm -rf "${OUTPUT_BASE}"' EXIT
+
+GOFLAGS=-mod=vendor go run k8s.io/code-generator/cmd/deepcopy-gen \
+    --input-dirs ${MODULE}/pkg/apis/core/v1alpha1 \
+    --output-base "${OUTPUT_BASE}" \
+    --go-header-file "${SCRIPT_ROOT}/hack/custom-boilerplate.go.txt" \
+    -O zz_generated.deepcopy
+
+cp -r "${OUTPUT_BASE}/${MODULE}/." "${SCRIPT_ROOT}/"
@@ -0,0 +1,4 @@
+package core
+
+// GroupName is the group name of the wasp API
+const GroupName = "wasp.io"
@@ -0,0 +1,70 @@
+/*
+Copyright 2023 The WASP Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SwapPolicy configures how swap is granted to the pods it selects
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type SwapPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec SwapPolicySpec `json:"spec"`
+}
+
+// SwapPolicySpec defines the selected pods and the swap settings applied to them
+type SwapPolicySpec struct {
+	// NamespaceSelector selects the namespaces of the pods the policy applies to.
+	// An empty or nil selector matches all namespaces.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// PodSelector selects the pods the policy applies to.
+	// An empty or nil selector matches all pods.
+	// +optional
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+	// Strategy is the swap allocation policy for burstable containers, e.g. proportional-to-request
+	// +optional
+	Strategy string `json:"strategy,omitempty"`
+	// FixedCap is the swap granted to every burstable container by the fixed-cap strategy
+	// +optional
+	FixedCap *resource.Quantity `json:"fixedCap,omitempty"`
+	// MaxSwap caps the swap of every container selected by the policy
+	// +optional
+	MaxSwap *resource.Quantity `json:"maxSwap,omitempty"`
+	// ExcludeCriticalPods prevents critical pods from getting swap, defaults to true
+	// +optional
+	ExcludeCriticalPods *bool `json:"excludeCriticalPods,omitempty"`
+	// Priority decides which policy applies when several policies select the same pod,
+	// the highest priority wins and ties are broken by name
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+}
+
+// SwapPolicyList is a list of SwapPolicies
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type SwapPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []SwapPolicy `json:"items"`
+}
@@ -16,6 +16,7 @@ import (
 
 type WaspClient interface {
 	RestClient() *rest.RESTClient
+	WaspV1alpha1RestClient() *rest.RESTClient
 	kubernetes.Interface
 	KubevirtClient() kubevirtclient.Interface
 	DiscoveryClient() discovery.DiscoveryInterface
@@ -26,6 +27,7 @@ type wasp struct {
 	master          string
 	kubeconfig      string
 	restClient      *rest.RESTClient
+	waspRestClient  *rest.RESTClient
 	config          *rest.Config
 	kubevirtClient  *kubevirtclient.Clientset
 	discoveryClient *discovery.DiscoveryClient
@@ -44,6 +46,10 @@ func (k wasp) Config() *rest.Config {
 func (k wasp) RestClient() *rest.RESTClient {
 	return k.restClient
 }
+
+func (k wasp) WaspV1alpha1RestClient() *rest.RESTClient {
+	return k.waspRestClient
+}
 func (k wasp) DiscoveryClient() discovery.DiscoveryInterface {
 	return k.discoveryClient
 }
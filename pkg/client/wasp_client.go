@@ -2,6 +2,7 @@ package client
 
 import (
 	"flag"
+	waspv1alpha1 "github.com/openshift-virtualization/wasp-agent/pkg/apis/core/v1alpha1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
@@ -22,7 +23,7 @@ var (
 )
 
 var (
-	SchemeBuilder  runtime.SchemeBuilder
+	SchemeBuilder  = runtime.NewSchemeBuilder(waspv1alpha1.AddToScheme)
 	Scheme         *runtime.Scheme
 	Codecs         serializer.CodecFactory
 	ParameterCodec runtime.ParameterCodec
@@ -94,6 +95,13 @@ func GetWaspClientFromRESTConfig(config *rest.Config) (WaspClient, error) {
 		return nil, err
 	}
 
+	waspRestConfig := shallowCopy
+	waspRestConfig.GroupVersion = &waspv1alpha1.SchemeGroupVersion
+	waspRestClient, err := rest.RESTClientFor(&waspRestConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	kubevirtClient, err := kubevirtclient.NewForConfig(&shallowCopy)
 	if err != nil {
 		return nil, err
@@ -113,6 +121,7 @@ func GetWaspClientFromRESTConfig(config *rest.Config) (WaspClient, error) {
 		master,
 		kubeconfig,
 		restClient,
+		waspRestClient,
 		&shallowCopy,
 		kubevirtClient,
 		discoveryClient,
@@ -14,9 +14,19 @@ const (
 )
 
 func HookScriptPath(suffix string) string {
-	return fmt.Sprintf("%s/oci-hook-swap-%s.sh", HookScriptDir, suffix)
+	return HookScriptPathIn(HookScriptDir, suffix)
 }
 
 func HookConfigPath(suffix string) string {
-	return fmt.Sprintf("%s/swap-for-burstable-%s.json", HookConfigDir, suffix)
+	return HookConfigPathIn(HookConfigDir, suffix)
+}
+
+// HookScriptPathIn returns the path of the hook script in a custom hook script directory
+func HookScriptPathIn(dir, suffix string) string {
+	return fmt.Sprintf("%s/oci-hook-swap-%s.sh", dir, suffix)
+}
+
+// HookConfigPathIn returns the path of the hook config in a custom hooks directory
+func HookConfigPathIn(dir, suffix string) string {
+	return fmt.Sprintf("%s/swap-for-burstable-%s.json", dir, suffix)
 }
@@ -20,4 +20,9 @@ var _ = Describe("Hook path generation", func() {
 		Expect(HookScriptPath("wasp-agent-abc12")).ToNot(Equal(HookScriptPath("wasp-agent-def34")))
 		Expect(HookConfigPath("wasp-agent-abc12")).ToNot(Equal(HookConfigPath("wasp-agent-def34")))
 	})
+
+	It("should generate the hook paths in custom directories", func() {
+		Expect(HookScriptPathIn("/host/var/opt", testSuffix)).To(Equal("/host/var/opt/oci-hook-swap-wasp-agent-abc12.sh"))
+		Expect(HookConfigPathIn("/host/etc/containers/oci/hooks.d", testSuffix)).To(Equal("/host/etc/containers/oci/hooks.d/swap-for-burstable-wasp-agent-abc12.json"))
+	})
 })
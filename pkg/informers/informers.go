@@ -2,6 +2,7 @@ package informers
 
 import (
 	"context"
+	waspv1alpha1 "github.com/openshift-virtualization/wasp-agent/pkg/apis/core/v1alpha1"
 	"github.com/openshift-virtualization/wasp-agent/pkg/client"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -10,12 +11,34 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/tools/cache"
+	kubevirtv1 "kubevirt.io/api/core/v1"
 	"time"
 )
 
-func GetPodInformer(waspCli client.WaspClient) cache.SharedIndexInformer {
-	listWatcher := NewListWatchFromClient(waspCli.CoreV1().RESTClient(), "pods", metav1.NamespaceAll, fields.Everything(), labels.Everything())
-	return cache.NewSharedIndexInformer(listWatcher, &v1.Pod{}, 1*time.Hour, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+// GetPodInformer returns an informer of the pods scheduled on the node
+func GetPodInformer(waspCli client.WaspClient, nodeName string, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	nodeSelector := fields.OneTermEqualSelector("spec.nodeName", nodeName)
+	listWatcher := NewListWatchFromClient(waspCli.CoreV1().RESTClient(), "pods", metav1.NamespaceAll, nodeSelector, labels.Everything())
+	return cache.NewSharedIndexInformer(listWatcher, &v1.Pod{}, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+}
+
+// GetVMIInformer returns an informer of the KubeVirt VMIs running on the node
+func GetVMIInformer(waspCli client.WaspClient, nodeName string) cache.SharedIndexInformer {
+	nodeSelector := labels.SelectorFromSet(labels.Set{kubevirtv1.NodeNameLabel: nodeName})
+	listWatcher := NewListWatchFromClient(waspCli.KubevirtClient().KubevirtV1().RESTClient(), "virtualmachineinstances", metav1.NamespaceAll, fields.Everything(), nodeSelector)
+	return cache.NewSharedIndexInformer(listWatcher, &kubevirtv1.VirtualMachineInstance{}, 1*time.Hour, cache.Indexers{})
+}
+
+// GetNamespaceInformer returns an informer of the namespaces, whose labels are matched by the SwapPolicy objects
+func GetNamespaceInformer(waspCli client.WaspClient) cache.SharedIndexInformer {
+	listWatcher := NewListWatchFromClient(waspCli.CoreV1().RESTClient(), "namespaces", metav1.NamespaceAll, fields.Everything(), labels.Everything())
+	return cache.NewSharedIndexInformer(listWatcher, &v1.Namespace{}, 1*time.Hour, cache.Indexers{})
+}
+
+// GetSwapPolicyInformer returns an informer of the cluster scoped SwapPolicy objects
+func GetSwapPolicyInformer(waspCli client.WaspClient) cache.SharedIndexInformer {
+	listWatcher := NewListWatchFromClient(waspCli.WaspV1alpha1RestClient(), "swappolicies", metav1.NamespaceAll, fields.Everything(), labels.Everything())
+	return cache.NewSharedIndexInformer(listWatcher, &waspv1alpha1.SwapPolicy{}, 1*time.Hour, cache.Indexers{})
 }
 
 // NewListWatchFromClient creates a new ListWatch from the specified client, resource, kubevirtNamespace and field selector.
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"github.com/machadovilaca/operator-observability/pkg/operatormetrics"
+)
+
+const (
+	EvictionEvicted           = "evicted"
+	EvictionBlocked           = "blocked"
+	EvictionFailed            = "failed"
+	EvictionDryRun            = "dry_run"
+	EvictionSignalUtilization = "swap_utilization"
+	EvictionSignalTraffic     = "swap_traffic"
+)
+
+var (
+	evictionMetrics = []operatormetrics.Metric{
+		evictions,
+		evictionSignals,
+	}
+
+	evictions = operatormetrics.NewCounterVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "evictions_total",
+			Help: "The number of pod evictions requested to relieve swap pressure by result",
+		},
+		[]string{"result"},
+	)
+
+	evictionSignals = operatormetrics.NewGaugeVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "eviction_signal_exceeded",
+			Help: "Whether a swap eviction signal of the node is above its threshold",
+		},
+		[]string{"signal"},
+	)
+)
+
+func IncEviction(result string) {
+	evictions.WithLabelValues(result).Inc()
+}
+
+func SetEvictionSignal(signal string, exceeded bool) {
+	value := 0.0
+	if exceeded {
+		value = 1
+	}
+	evictionSignals.WithLabelValues(signal).Set(value)
+}
@@ -0,0 +1,22 @@
+package metrics
+
+import (
+	"github.com/machadovilaca/operator-observability/pkg/operatormetrics"
+)
+
+const metricPrefix = "wasp_"
+
+// SetupMetrics registers the metrics exported by the agent
+func SetupMetrics() error {
+	err := operatormetrics.RegisterMetrics(
+		swapMetrics,
+		reconcileMetrics,
+		runtimeMetrics,
+		evictionMetrics,
+	)
+	if err != nil {
+		return err
+	}
+
+	return operatormetrics.RegisterCollector(swapUsageCollector)
+}
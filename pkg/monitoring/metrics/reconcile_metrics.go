@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/machadovilaca/operator-observability/pkg/operatormetrics"
+)
+
+const (
+	ReasonCRILookupFailed     = "cri_lookup_failed"
+	ReasonCgroupWriteFailed   = "cgroup_write_failed"
+	ReasonContainerNotRunning = "container_not_running"
+
+	// ReasonContainerNotStarted is the reason a container is skipped until it runs, which is not an error
+	ReasonContainerNotStarted = "container_not_started"
+)
+
+var (
+	reconcileMetrics = []operatormetrics.Metric{
+		reconcileTotal,
+		reconcileErrors,
+		reconcileSkips,
+		workQueueDepth,
+		lastFullResync,
+		swapLimitDrifts,
+		swapLimitsRestored,
+	}
+
+	reconcileTotal = operatormetrics.NewCounter(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "reconcile_total",
+			Help: "The number of pod reconciliations",
+		},
+	)
+
+	reconcileErrors = operatormetrics.NewCounterVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "reconcile_errors_total",
+			Help: "The number of container reconciliation errors by reason",
+		},
+		[]string{"reason"},
+	)
+
+	reconcileSkips = operatormetrics.NewCounterVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "reconcile_skips_total",
+			Help: "The number of containers skipped by a reconciliation by reason",
+		},
+		[]string{"reason"},
+	)
+
+	workQueueDepth = operatormetrics.NewGauge(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "workqueue_depth",
+			Help: "The number of pods waiting to be reconciled",
+		},
+	)
+
+	swapLimitDrifts = operatormetrics.NewCounter(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "swap_limit_drifts_total",
+			Help: "The number of container swap limits found changed outside of the agent",
+		},
+	)
+
+	swapLimitsRestored = operatormetrics.NewCounter(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "swap_limits_restored_total",
+			Help: "The number of container swap limits reverted when the agent is uninstalled",
+		},
+	)
+
+	lastFullResync = operatormetrics.NewGauge(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "last_full_resync_timestamp_seconds",
+			Help: "The time of the last resync of all the pods on the node, in seconds since the epoch",
+		},
+	)
+)
+
+func IncReconcile() {
+	reconcileTotal.Inc()
+}
+
+func IncReconcileError(reason string) {
+	reconcileErrors.WithLabelValues(reason).Inc()
+}
+
+func IncReconcileSkip(reason string) {
+	reconcileSkips.WithLabelValues(reason).Inc()
+}
+
+func SetWorkQueueDepth(depth int) {
+	workQueueDepth.Set(float64(depth))
+}
+
+func SetLastFullResync(t time.Time) {
+	lastFullResync.Set(float64(t.Unix()))
+}
+
+func IncSwapLimitDrift() {
+	swapLimitDrifts.Inc()
+}
+
+func IncSwapLimitRestored() {
+	swapLimitsRestored.Inc()
+}
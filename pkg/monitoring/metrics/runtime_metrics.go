@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"github.com/machadovilaca/operator-observability/pkg/operatormetrics"
+)
+
+var (
+	runtimeMetrics = []operatormetrics.Metric{
+		containerRuntimeUp,
+		containerRuntimeErrors,
+	}
+
+	containerRuntimeUp = operatormetrics.NewGaugeVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "container_runtime_up",
+			Help: "Whether the CRI socket of the container runtime is reachable (1) or not (0)",
+		},
+		[]string{"runtime"},
+	)
+
+	containerRuntimeErrors = operatormetrics.NewCounterVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "container_runtime_errors_total",
+			Help: "The number of CRI calls that failed because the container runtime was unreachable or timed out",
+		},
+		[]string{"runtime"},
+	)
+)
+
+func SetContainerRuntimeUp(runtime string, up bool) {
+	value := 0.0
+	if up {
+		value = 1
+	}
+	containerRuntimeUp.WithLabelValues(runtime).Set(value)
+}
+
+func IncContainerRuntimeError(runtime string) {
+	containerRuntimeErrors.WithLabelValues(runtime).Inc()
+}
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"math"
+
+	"github.com/machadovilaca/operator-observability/pkg/operatormetrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	ExclusionNotBurstable  = "not_burstable"
+	ExclusionCriticalPod   = "critical_pod"
+	ExclusionNamespace     = "excluded_namespace"
+	ExclusionLabels        = "excluded_labels"
+	ExclusionPriorityClass = "excluded_priority_class"
+	ExclusionNotIncluded   = "not_included"
+)
+
+var (
+	containerLabels = []string{"namespace", "pod", "container"}
+
+	swapMetrics = []operatormetrics.Metric{
+		containerSwapMax,
+		containerSwapMaxCurrent,
+		containerSwapHigh,
+		containerMemoryHigh,
+		podSwapMax,
+		podSwapExcluded,
+		nodeMemoryCapacity,
+		nodeSwapCapacity,
+		qosSwapMax,
+	}
+
+	containerSwapMax = operatormetrics.NewGaugeVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "container_swap_max_bytes",
+			Help: "The memory.swap.max value configured by the agent for a container, +Inf when swap is not capped",
+		},
+		containerLabels,
+	)
+
+	containerSwapMaxCurrent = operatormetrics.NewGaugeVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "container_swap_max_current_bytes",
+			Help: "The swap limit found in the cgroup of a container in dry-run mode, +Inf when swap is not capped",
+		},
+		containerLabels,
+	)
+
+	containerSwapHigh = operatormetrics.NewGaugeVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "container_swap_high_bytes",
+			Help: "The memory.swap.high value configured by the agent for a container, +Inf when swap is not throttled",
+		},
+		containerLabels,
+	)
+
+	containerMemoryHigh = operatormetrics.NewGaugeVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "container_memory_high_bytes",
+			Help: "The memory.high value configured by the agent for a container, +Inf when memory is not throttled",
+		},
+		containerLabels,
+	)
+
+	podSwapMax = operatormetrics.NewGaugeVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "pod_swap_max_bytes",
+			Help: "The memory.swap.max value configured by the agent for a pod cgroup, +Inf when swap is not capped",
+		},
+		[]string{"namespace", "pod"},
+	)
+
+	podSwapExcluded = operatormetrics.NewGaugeVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "pod_swap_excluded",
+			Help: "Set to 1 for the pods whose containers get no swap, with the reason of the exclusion",
+		},
+		[]string{"namespace", "pod", "reason"},
+	)
+
+	nodeMemoryCapacity = operatormetrics.NewGaugeVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "node_memory_capacity_bytes",
+			Help: "The memory of the node the swap limits are computed with, by the source it is read from",
+		},
+		[]string{"source"},
+	)
+
+	qosSwapMax = operatormetrics.NewGaugeVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "qos_swap_max_bytes",
+			Help: "The memory.swap.max value configured by the agent for the kubepods or a QoS cgroup",
+		},
+		[]string{"cgroup"},
+	)
+
+	nodeSwapCapacity = operatormetrics.NewGauge(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "node_swap_capacity_bytes",
+			Help: "The swap of the node the swap limits are computed with",
+		},
+	)
+)
+
+// SetNodeCapacity records the memory and swap of the node the swap limits are computed with,
+// source is where the memory was read from
+func SetNodeCapacity(source string, memory, swap int64) {
+	nodeMemoryCapacity.Reset()
+	nodeMemoryCapacity.WithLabelValues(source).Set(float64(memory))
+	nodeSwapCapacity.Set(float64(swap))
+}
+
+// SetContainerSwapMax records the swap limit of a container, a negative limit means swap is not capped
+func SetContainerSwapMax(namespace, pod, container string, swapMax int64) {
+	containerSwapMax.WithLabelValues(namespace, pod, container).Set(swapMaxValue(swapMax))
+}
+
+// SetContainerSwapMaxCurrent records the swap limit found in the cgroup of a container in dry-run mode,
+// a negative limit means swap is not capped
+func SetContainerSwapMaxCurrent(namespace, pod, container string, swapMax int64) {
+	containerSwapMaxCurrent.WithLabelValues(namespace, pod, container).Set(swapMaxValue(swapMax))
+}
+
+// SetContainerSwapHigh records the memory.swap.high value of a container, a negative value means swap is not throttled
+func SetContainerSwapHigh(namespace, pod, container string, swapHigh int64) {
+	containerSwapHigh.WithLabelValues(namespace, pod, container).Set(swapMaxValue(swapHigh))
+}
+
+// SetContainerMemoryHigh records the memory.high value of a container, a negative value means memory is not throttled
+func SetContainerMemoryHigh(namespace, pod, container string, memoryHigh int64) {
+	containerMemoryHigh.WithLabelValues(namespace, pod, container).Set(swapMaxValue(memoryHigh))
+}
+
+// SetPodSwapMax records the swap limit of a pod cgroup, a negative limit means swap is not capped
+func SetPodSwapMax(namespace, pod string, swapMax int64) {
+	podSwapMax.WithLabelValues(namespace, pod).Set(swapMaxValue(swapMax))
+}
+
+// SetPodSwapExclusion records why a pod gets no swap, an empty reason means the pod is eligible for swap
+func SetPodSwapExclusion(namespace, pod, reason string) {
+	podSwapExcluded.DeletePartialMatch(prometheus.Labels{"namespace": namespace, "pod": pod})
+	if reason != "" {
+		podSwapExcluded.WithLabelValues(namespace, pod, reason).Set(1)
+	}
+}
+
+func swapMaxValue(swapMax int64) float64 {
+	if swapMax < 0 {
+		return math.Inf(1)
+	}
+	return float64(swapMax)
+}
+
+// DeletePodMetrics removes the series of a pod and of all its containers
+func DeletePodMetrics(namespace, pod string) {
+	podLabels := prometheus.Labels{"namespace": namespace, "pod": pod}
+	containerSwapMax.DeletePartialMatch(podLabels)
+	containerSwapMaxCurrent.DeletePartialMatch(podLabels)
+	containerSwapHigh.DeletePartialMatch(podLabels)
+	containerMemoryHigh.DeletePartialMatch(podLabels)
+	podSwapMax.Delete(podLabels)
+	podSwapExcluded.DeletePartialMatch(podLabels)
+}
+
+// SetQoSSwapMax records the swap limit of the kubepods or a QoS cgroup, a negative limit means swap is not capped
+func SetQoSSwapMax(cgroup string, swapMax int64) {
+	qosSwapMax.WithLabelValues(cgroup).Set(swapMaxValue(swapMax))
+}
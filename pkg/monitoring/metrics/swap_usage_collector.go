@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/machadovilaca/operator-observability/pkg/operatormetrics"
+)
+
+// ContainerSwapUsage is a sample of the swap usage of a container
+type ContainerSwapUsage struct {
+	Namespace      string
+	Pod            string
+	Container      string
+	SwapCurrent    uint64
+	SwapEventsMax  uint64
+	SwapEventsFail uint64
+	// SwapIn and SwapOut are nil when the kernel does not report them per cgroup
+	SwapIn  *uint64
+	SwapOut *uint64
+}
+
+var (
+	swapUsageLock    sync.Mutex
+	swapUsageSamples []ContainerSwapUsage
+
+	swapUsageCollector = operatormetrics.Collector{
+		Metrics: []operatormetrics.Metric{
+			containerSwapCurrent,
+			containerSwapEventsMax,
+			containerSwapEventsFail,
+			containerSwapIn,
+			containerSwapOut,
+		},
+		CollectCallback: collectSwapUsage,
+	}
+
+	containerSwapCurrent = operatormetrics.NewGaugeVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "container_swap_current_bytes",
+			Help: "The memory.swap.current value of a container",
+		},
+		containerLabels,
+	)
+
+	containerSwapEventsMax = operatormetrics.NewCounterVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "container_swap_events_max_total",
+			Help: "The number of times the swap usage of a container was about to exceed memory.swap.max",
+		},
+		containerLabels,
+	)
+
+	containerSwapEventsFail = operatormetrics.NewCounterVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "container_swap_events_fail_total",
+			Help: "The number of times a swap allocation of a container failed",
+		},
+		containerLabels,
+	)
+
+	containerSwapIn = operatormetrics.NewCounterVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "container_swap_in_pages_total",
+			Help: "The number of pages swapped in by a container",
+		},
+		containerLabels,
+	)
+
+	containerSwapOut = operatormetrics.NewCounterVec(
+		operatormetrics.MetricOpts{
+			Name: metricPrefix + "container_swap_out_pages_total",
+			Help: "The number of pages swapped out by a container",
+		},
+		containerLabels,
+	)
+)
+
+// SetContainerSwapUsage replaces the samples exported on the next scrape
+func SetContainerSwapUsage(samples []ContainerSwapUsage) {
+	swapUsageLock.Lock()
+	defer swapUsageLock.Unlock()
+	swapUsageSamples = samples
+}
+
+func collectSwapUsage() []operatormetrics.CollectorResult {
+	swapUsageLock.Lock()
+	defer swapUsageLock.Unlock()
+
+	var results []operatormetrics.CollectorResult
+	for _, sample := range swapUsageSamples {
+		labels := []string{sample.Namespace, sample.Pod, sample.Container}
+		results = append(results,
+			operatormetrics.CollectorResult{Metric: containerSwapCurrent, Labels: labels, Value: float64(sample.SwapCurrent)},
+			operatormetrics.CollectorResult{Metric: containerSwapEventsMax, Labels: labels, Value: float64(sample.SwapEventsMax)},
+			operatormetrics.CollectorResult{Metric: containerSwapEventsFail, Labels: labels, Value: float64(sample.SwapEventsFail)},
+		)
+		if sample.SwapIn != nil {
+			results = append(results, operatormetrics.CollectorResult{Metric: containerSwapIn, Labels: labels, Value: float64(*sample.SwapIn)})
+		}
+		if sample.SwapOut != nil {
+			results = append(results, operatormetrics.CollectorResult{Metric: containerSwapOut, Labels: labels, Value: float64(*sample.SwapOut)})
+		}
+	}
+	return results
+}
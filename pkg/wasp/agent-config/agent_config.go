@@ -0,0 +1,333 @@
+package agent_config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	APIVersion = "wasp.io/v1alpha1"
+	Kind       = "AgentConfiguration"
+)
+
+// AgentConfiguration is the configuration file of the agent, usually mounted from a ConfigMap.
+// Fields that are not set in the file keep the value of the corresponding command line flag.
+type AgentConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// SwapPolicy is the swap allocation policy of burstable containers
+	SwapPolicy SwapPolicyConfiguration `json:"swapPolicy"`
+	// PodSwapLimit also caps the swap of the pod cgroups
+	PodSwapLimit bool `json:"podSwapLimit"`
+	// MemoryCapacity is the node memory the swap of the containers is proportional to
+	MemoryCapacity MemoryCapacityConfiguration `json:"memoryCapacity"`
+	// SystemSwapReserve is the swap kept for the system services, the pods share the rest of the node swap
+	SystemSwapReserve resource.Quantity `json:"systemSwapReserve"`
+	// KubepodsSwapLimit caps the swap of the kubepods cgroup to the swap the pods share
+	KubepodsSwapLimit bool `json:"kubepodsSwapLimit"`
+	// BurstableSwapLimit also caps the swap of the burstable QoS cgroup
+	BurstableSwapLimit bool `json:"burstableSwapLimit"`
+	// Inclusions select the pods that get swap, all the pods are eligible for swap when empty
+	Inclusions PodRulesConfiguration `json:"inclusions"`
+	// Exclusions select the pods that never get swap, they take precedence over Inclusions
+	Exclusions PodRulesConfiguration `json:"exclusions"`
+
+	// ResyncPeriod is how often all the pods on the node are reconciled
+	ResyncPeriod metav1.Duration `json:"resyncPeriod"`
+	// PodInformerResyncPeriod is the resync period of the informer of the pods on the node
+	PodInformerResyncPeriod metav1.Duration `json:"podInformerResyncPeriod"`
+	// SwapUsageInterval is how often the swap usage of the managed containers is sampled
+	SwapUsageInterval metav1.Duration `json:"swapUsageInterval"`
+	// CapacityCheckInterval is how often the memory and swap of the node are read to detect capacity changes
+	CapacityCheckInterval metav1.Duration `json:"capacityCheckInterval"`
+	// Workers is the number of pods reconciled concurrently
+	Workers int `json:"workers"`
+	// RateLimiter limits the retries of the pods whose reconciliation failed
+	RateLimiter RateLimiterConfiguration `json:"rateLimiter"`
+
+	// ContainerRuntime selects the container runtime instead of detecting it
+	ContainerRuntime ContainerRuntimeConfiguration `json:"containerRuntime"`
+	// CgroupRoot is where the cgroup hierarchy of the node is mounted in the agent
+	CgroupRoot string `json:"cgroupRoot"`
+	// Hooks are the directories the OCI hook is installed into
+	Hooks HooksConfiguration `json:"hooks"`
+	// DryRun computes and reports the swap limits without changing the cgroups or installing the OCI hook
+	DryRun bool `json:"dryRun"`
+	// StartupTaint taints the node until the agent manages the swap of the pods on the node
+	StartupTaint bool `json:"startupTaint"`
+	// Eviction evicts burstable pods when the node runs out of swap
+	Eviction EvictionConfiguration `json:"eviction"`
+}
+
+type SwapPolicyConfiguration struct {
+	// Name is one of the swap policies of the agent, e.g. proportional-to-request
+	Name string `json:"name"`
+	// FixedCap is the swap granted to every burstable container by the fixed-cap policy
+	FixedCap *resource.Quantity `json:"fixedCap,omitempty"`
+	// SwapHighPercent sets memory.swap.high of the containers to this percentage of their swap limit, 0 leaves it unmanaged
+	SwapHighPercent int `json:"swapHighPercent,omitempty"`
+	// MemoryHighPercent sets memory.high of the containers between their memory request (0) and limit (100),
+	// 0 leaves it unmanaged
+	MemoryHighPercent int `json:"memoryHighPercent,omitempty"`
+}
+
+type MemoryCapacityConfiguration struct {
+	// Source is host, allocatable or fixed
+	Source string `json:"source"`
+	// Fixed is the node memory of the fixed source
+	Fixed *resource.Quantity `json:"fixed,omitempty"`
+}
+
+// PodRulesConfiguration selects pods, a pod is selected when it matches any of the rules
+type PodRulesConfiguration struct {
+	// Namespaces are glob patterns of namespace names, e.g. openshift-*
+	Namespaces []string `json:"namespaces,omitempty"`
+	// PodSelector selects pods by their labels
+	PodSelector *metav1.LabelSelector `json:"podSelector,omitempty"`
+	// PriorityClassNames are the priority classes of the selected pods
+	PriorityClassNames []string `json:"priorityClassNames,omitempty"`
+}
+
+func (r PodRulesConfiguration) validate(field string) error {
+	for _, pattern := range r.Namespaces {
+		if _, err := path.Match(pattern, ""); err != nil || pattern == "" {
+			return fmt.Errorf("%s.namespaces: invalid pattern %q", field, pattern)
+		}
+	}
+	if r.PodSelector != nil {
+		if _, err := metav1.LabelSelectorAsSelector(r.PodSelector); err != nil {
+			return fmt.Errorf("%s.podSelector: %w", field, err)
+		}
+	}
+	for _, name := range r.PriorityClassNames {
+		if name == "" {
+			return fmt.Errorf("%s.priorityClassNames must not contain empty names", field)
+		}
+	}
+	return nil
+}
+
+func (r PodRulesConfiguration) deepCopy() PodRulesConfiguration {
+	rules := PodRulesConfiguration{PodSelector: r.PodSelector.DeepCopy()}
+	if r.Namespaces != nil {
+		rules.Namespaces = append([]string{}, r.Namespaces...)
+	}
+	if r.PriorityClassNames != nil {
+		rules.PriorityClassNames = append([]string{}, r.PriorityClassNames...)
+	}
+	return rules
+}
+
+type EvictionConfiguration struct {
+	// Enabled evicts the burstable pods using the most swap when one of the thresholds is exceeded
+	Enabled bool `json:"enabled"`
+	// SwapUtilizationThresholdPercent is the percentage of the node swap in use above which pods are evicted
+	SwapUtilizationThresholdPercent int `json:"swapUtilizationThresholdPercent"`
+	// SwapTrafficThreshold is the number of pages swapped in and out per second above which pods are evicted,
+	// 0 disables the signal
+	SwapTrafficThreshold int64 `json:"swapTrafficThreshold"`
+	// MinPodSwap is the swap a pod must use to be evicted
+	MinPodSwap resource.Quantity `json:"minPodSwap"`
+	// Interval is how often the eviction signals are sampled
+	Interval metav1.Duration `json:"interval"`
+	// Cooldown is the time after an eviction during which no other pod is evicted
+	Cooldown metav1.Duration `json:"cooldown"`
+}
+
+func (e EvictionConfiguration) validate() error {
+	if e.SwapUtilizationThresholdPercent < 1 || e.SwapUtilizationThresholdPercent > 100 {
+		return fmt.Errorf("eviction.swapUtilizationThresholdPercent must be between 1 and 100, got %d", e.SwapUtilizationThresholdPercent)
+	}
+	if e.SwapTrafficThreshold < 0 {
+		return fmt.Errorf("eviction.swapTrafficThreshold must not be negative, got %d", e.SwapTrafficThreshold)
+	}
+	if e.MinPodSwap.Sign() < 0 {
+		return fmt.Errorf("eviction.minPodSwap must not be negative, got %v", e.MinPodSwap.String())
+	}
+	if e.Interval.Duration <= 0 {
+		return fmt.Errorf("eviction.interval must be positive, got %v", e.Interval.Duration)
+	}
+	if e.Cooldown.Duration < 0 {
+		return fmt.Errorf("eviction.cooldown must not be negative, got %v", e.Cooldown.Duration)
+	}
+	return nil
+}
+
+type RateLimiterConfiguration struct {
+	// BaseDelay is the delay before retrying to reconcile a pod, doubled on each failure
+	BaseDelay metav1.Duration `json:"baseDelay"`
+	// MaxDelay is the maximum delay before retrying to reconcile a pod
+	MaxDelay metav1.Duration `json:"maxDelay"`
+	// QPS is the overall rate of pod reconciliation retries per second
+	QPS float64 `json:"qps"`
+	// Burst is the burst of pod reconciliation retries
+	Burst int `json:"burst"`
+}
+
+func (r RateLimiterConfiguration) validate() error {
+	if r.BaseDelay.Duration <= 0 {
+		return fmt.Errorf("rateLimiter.baseDelay must be positive, got %v", r.BaseDelay.Duration)
+	}
+	if r.MaxDelay.Duration < r.BaseDelay.Duration {
+		return fmt.Errorf("rateLimiter.maxDelay must not be lower than rateLimiter.baseDelay, got %v", r.MaxDelay.Duration)
+	}
+	if r.QPS <= 0 {
+		return fmt.Errorf("rateLimiter.qps must be positive, got %v", r.QPS)
+	}
+	if r.Burst < 1 {
+		return fmt.Errorf("rateLimiter.burst must be at least 1, got %d", r.Burst)
+	}
+	return nil
+}
+
+type ContainerRuntimeConfiguration struct {
+	// Name is cri-o or containerd, the runtime is detected from its socket when empty
+	Name string `json:"name,omitempty"`
+	// SocketPath is the path of the CRI socket on the node, the default socket of the runtime when empty
+	SocketPath string `json:"socketPath,omitempty"`
+	// Timeout of the calls to the container runtime
+	Timeout metav1.Duration `json:"timeout"`
+}
+
+type HooksConfiguration struct {
+	// ScriptDir is where the OCI hook script is installed, as seen from the agent
+	ScriptDir string `json:"scriptDir"`
+	// ConfigDir is the CRI-O hooks directory, as seen from the agent
+	ConfigDir string `json:"configDir"`
+}
+
+// Load reads the configuration file on top of defaults.
+// A missing file is not an error, the defaults are returned so that the ConfigMap can be created later.
+func Load(path string, defaults *AgentConfiguration) (*AgentConfiguration, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return defaults.DeepCopy(), nil
+	} else if err != nil {
+		return nil, err
+	}
+	return Parse(content, defaults)
+}
+
+// Parse decodes and validates a configuration on top of defaults
+func Parse(content []byte, defaults *AgentConfiguration) (*AgentConfiguration, error) {
+	config := defaults.DeepCopy()
+	if len(bytes.TrimSpace(content)) == 0 {
+		return config, nil
+	}
+	// only the fields set in the file replace the defaults
+	config.TypeMeta = metav1.TypeMeta{}
+	if err := yaml.UnmarshalStrict(content, config); err != nil {
+		return nil, fmt.Errorf("invalid agent configuration: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid agent configuration: %w", err)
+	}
+	return config, nil
+}
+
+// Validate checks the fields that do not depend on the rest of the agent,
+// the swap policy and the memory capacity source are validated when they are created
+func (c *AgentConfiguration) Validate() error {
+	if c.APIVersion != APIVersion || c.Kind != Kind {
+		return fmt.Errorf("unsupported apiVersion %q and kind %q, expected %s %s", c.APIVersion, c.Kind, APIVersion, Kind)
+	}
+	for name, duration := range map[string]metav1.Duration{
+		"resyncPeriod":             c.ResyncPeriod,
+		"podInformerResyncPeriod":  c.PodInformerResyncPeriod,
+		"swapUsageInterval":        c.SwapUsageInterval,
+		"capacityCheckInterval":    c.CapacityCheckInterval,
+		"containerRuntime.timeout": c.ContainerRuntime.Timeout,
+	} {
+		if duration.Duration <= 0 {
+			return fmt.Errorf("%s must be positive, got %v", name, duration.Duration)
+		}
+	}
+	if c.Workers < 1 {
+		return fmt.Errorf("workers must be at least 1, got %d", c.Workers)
+	}
+	if err := c.RateLimiter.validate(); err != nil {
+		return err
+	}
+	if c.SwapPolicy.FixedCap != nil && c.SwapPolicy.FixedCap.Sign() < 0 {
+		return fmt.Errorf("swapPolicy.fixedCap must not be negative, got %v", c.SwapPolicy.FixedCap.String())
+	}
+	for name, percent := range map[string]int{
+		"swapPolicy.swapHighPercent":   c.SwapPolicy.SwapHighPercent,
+		"swapPolicy.memoryHighPercent": c.SwapPolicy.MemoryHighPercent,
+	} {
+		if percent < 0 || percent > 100 {
+			return fmt.Errorf("%s must be between 0 and 100, got %d", name, percent)
+		}
+	}
+	if c.MemoryCapacity.Fixed != nil && c.MemoryCapacity.Fixed.Sign() < 0 {
+		return fmt.Errorf("memoryCapacity.fixed must not be negative, got %v", c.MemoryCapacity.Fixed.String())
+	}
+	if c.SystemSwapReserve.Sign() < 0 {
+		return fmt.Errorf("systemSwapReserve must not be negative, got %v", c.SystemSwapReserve.String())
+	}
+	if err := c.Inclusions.validate("inclusions"); err != nil {
+		return err
+	}
+	if err := c.Exclusions.validate("exclusions"); err != nil {
+		return err
+	}
+	if err := c.Eviction.validate(); err != nil {
+		return err
+	}
+	for name, path := range map[string]string{
+		"cgroupRoot":                  c.CgroupRoot,
+		"hooks.scriptDir":             c.Hooks.ScriptDir,
+		"hooks.configDir":             c.Hooks.ConfigDir,
+		"containerRuntime.socketPath": c.ContainerRuntime.SocketPath,
+	} {
+		if path != "" && !filepath.IsAbs(path) {
+			return fmt.Errorf("%s must be an absolute path, got %q", name, path)
+		}
+	}
+	if c.CgroupRoot == "" || c.Hooks.ScriptDir == "" || c.Hooks.ConfigDir == "" {
+		return fmt.Errorf("cgroupRoot, hooks.scriptDir and hooks.configDir must be set")
+	}
+	if c.ContainerRuntime.SocketPath != "" && c.ContainerRuntime.Name == "" {
+		return fmt.Errorf("containerRuntime.name must be set with containerRuntime.socketPath")
+	}
+	return nil
+}
+
+// Static returns the configuration without the fields that are reloaded at runtime,
+// a change of the static configuration only applies after a restart of the agent
+func (c *AgentConfiguration) Static() *AgentConfiguration {
+	config := c.DeepCopy()
+	config.SwapPolicy = SwapPolicyConfiguration{}
+	config.PodSwapLimit = false
+	config.Inclusions = PodRulesConfiguration{}
+	config.Exclusions = PodRulesConfiguration{}
+	return config
+}
+
+func (c *AgentConfiguration) DeepCopy() *AgentConfiguration {
+	if c == nil {
+		return nil
+	}
+	config := *c
+	if c.SwapPolicy.FixedCap != nil {
+		fixedCap := c.SwapPolicy.FixedCap.DeepCopy()
+		config.SwapPolicy.FixedCap = &fixedCap
+	}
+	if c.MemoryCapacity.Fixed != nil {
+		fixed := c.MemoryCapacity.Fixed.DeepCopy()
+		config.MemoryCapacity.Fixed = &fixed
+	}
+	config.SystemSwapReserve = c.SystemSwapReserve.DeepCopy()
+	config.Inclusions = c.Inclusions.deepCopy()
+	config.Exclusions = c.Exclusions.deepCopy()
+	config.Eviction.MinPodSwap = c.Eviction.MinPodSwap.DeepCopy()
+	return &config
+}
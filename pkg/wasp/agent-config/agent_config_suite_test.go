@@ -0,0 +1,13 @@
+package agent_config
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestAgentConfig(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "AgentConfig Suite")
+}
@@ -0,0 +1,220 @@
+package agent_config
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newDefaults() *AgentConfiguration {
+	return &AgentConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: APIVersion,
+			Kind:       Kind,
+		},
+		SwapPolicy:              SwapPolicyConfiguration{Name: "proportional-to-request"},
+		ResyncPeriod:            metav1.Duration{Duration: 10 * time.Minute},
+		PodInformerResyncPeriod: metav1.Duration{Duration: time.Hour},
+		SwapUsageInterval:       metav1.Duration{Duration: 30 * time.Second},
+		CapacityCheckInterval:   metav1.Duration{Duration: time.Minute},
+		Workers:                 1,
+		ContainerRuntime:        ContainerRuntimeConfiguration{Timeout: metav1.Duration{Duration: 5 * time.Second}},
+		CgroupRoot:              "/host/sys/fs/cgroup",
+		RateLimiter: RateLimiterConfiguration{
+			BaseDelay: metav1.Duration{Duration: 5 * time.Millisecond},
+			MaxDelay:  metav1.Duration{Duration: 1000 * time.Second},
+			QPS:       10,
+			Burst:     100,
+		},
+		Hooks: HooksConfiguration{
+			ScriptDir: "/host/opt",
+			ConfigDir: "/host/run/containers/oci/hooks.d",
+		},
+		Eviction: EvictionConfiguration{
+			SwapUtilizationThresholdPercent: 90,
+			Interval:                        metav1.Duration{Duration: 10 * time.Second},
+			Cooldown:                        metav1.Duration{Duration: 5 * time.Minute},
+		},
+	}
+}
+
+var _ = Describe("AgentConfiguration", func() {
+	var defaults *AgentConfiguration
+
+	BeforeEach(func() {
+		defaults = newDefaults()
+	})
+
+	Context("Parse", func() {
+		It("should return the defaults for an empty file", func() {
+			config, err := Parse([]byte("\n"), defaults)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).To(Equal(defaults))
+		})
+
+		It("should only replace the fields set in the file", func() {
+			config, err := Parse([]byte(`
+apiVersion: wasp.io/v1alpha1
+kind: AgentConfiguration
+swapPolicy:
+  name: fixed-cap
+  fixedCap: 1Gi
+exclusions:
+  namespaces: [kube-system]
+  podSelector:
+    matchLabels:
+      app: db
+  priorityClassNames: [system-cluster-critical]
+workers: 4
+`), defaults)
+			Expect(err).ToNot(HaveOccurred())
+
+			expected := newDefaults()
+			fixedCap := resource.MustParse("1Gi")
+			expected.SwapPolicy = SwapPolicyConfiguration{Name: "fixed-cap", FixedCap: &fixedCap}
+			expected.Exclusions = PodRulesConfiguration{
+				Namespaces:         []string{"kube-system"},
+				PodSelector:        &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+				PriorityClassNames: []string{"system-cluster-critical"},
+			}
+			expected.Workers = 4
+			Expect(config.SwapPolicy.FixedCap.Cmp(fixedCap)).To(Equal(0))
+			config.SwapPolicy.FixedCap = &fixedCap
+			Expect(config).To(Equal(expected))
+		})
+
+		It("should parse the eviction thresholds", func() {
+			config, err := Parse([]byte(`
+apiVersion: wasp.io/v1alpha1
+kind: AgentConfiguration
+eviction:
+  enabled: true
+  swapUtilizationThresholdPercent: 80
+  minPodSwap: 100Mi
+`), defaults)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Eviction.Enabled).To(BeTrue())
+			Expect(config.Eviction.SwapUtilizationThresholdPercent).To(Equal(80))
+			Expect(config.Eviction.MinPodSwap.Value()).To(Equal(int64(100 * 1024 * 1024)))
+			Expect(config.Eviction.Cooldown).To(Equal(defaults.Eviction.Cooldown))
+		})
+
+		It("should not modify the defaults", func() {
+			_, err := Parse([]byte(`
+apiVersion: wasp.io/v1alpha1
+kind: AgentConfiguration
+workers: 4
+`), defaults)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(defaults).To(Equal(newDefaults()))
+		})
+
+		DescribeTable("should reject invalid configurations", func(content string) {
+			_, err := Parse([]byte(content), defaults)
+			Expect(err).To(HaveOccurred())
+		},
+			Entry("missing apiVersion and kind", "workers: 2"),
+			Entry("unsupported apiVersion", "apiVersion: wasp.io/v2\nkind: AgentConfiguration"),
+			Entry("unknown field", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\nswapPolcy: {}"),
+			Entry("no workers", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\nworkers: 0"),
+			Entry("no rate limiter base delay", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\nrateLimiter: {baseDelay: 0s}"),
+			Entry("rate limiter max delay below base delay", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\nrateLimiter: {baseDelay: 1s, maxDelay: 100ms}"),
+			Entry("no rate limiter qps", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\nrateLimiter: {qps: 0}"),
+			Entry("no rate limiter burst", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\nrateLimiter: {burst: 0}"),
+			Entry("negative duration", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\nresyncPeriod: -1m"),
+			Entry("negative fixed memory capacity", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\nmemoryCapacity: {source: fixed, fixed: -1Gi}"),
+			Entry("negative system swap reserve", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\nsystemSwapReserve: -1Gi"),
+			Entry("swap high above 100%", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\nswapPolicy: {swapHighPercent: 120}"),
+			Entry("negative memory high", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\nswapPolicy: {memoryHighPercent: -1}"),
+			Entry("negative fixed cap", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\nswapPolicy: {fixedCap: -1Gi}"),
+			Entry("empty namespace", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\nexclusions: {namespaces: ['']}"),
+			Entry("invalid namespace pattern", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\ninclusions: {namespaces: ['vms-[']}"),
+			Entry("invalid pod selector", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\nexclusions: {podSelector: {matchExpressions: [{key: app, operator: Equal}]}}"),
+			Entry("empty priority class", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\nexclusions: {priorityClassNames: ['']}"),
+			Entry("relative cgroup root", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\ncgroupRoot: sys/fs/cgroup"),
+			Entry("eviction threshold above 100%", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\neviction: {swapUtilizationThresholdPercent: 101}"),
+			Entry("negative eviction traffic threshold", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\neviction: {swapTrafficThreshold: -1}"),
+			Entry("no eviction interval", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\neviction: {interval: 0s}"),
+			Entry("socket without runtime name", "apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\ncontainerRuntime: {socketPath: /run/crio.sock}"),
+		)
+	})
+
+	Context("Load", func() {
+		It("should return the defaults when the file does not exist", func() {
+			config, err := Load(filepath.Join(GinkgoT().TempDir(), "config.yaml"), defaults)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).To(Equal(defaults))
+			Expect(config).ToNot(BeIdenticalTo(defaults))
+		})
+	})
+
+	Context("Static", func() {
+		It("should ignore the fields reloaded at runtime", func() {
+			config := defaults.DeepCopy()
+			config.SwapPolicy.Name = "fixed-cap"
+			config.PodSwapLimit = true
+			config.Inclusions.Namespaces = []string{"vms-*"}
+			config.Exclusions.Namespaces = []string{"kube-system"}
+			Expect(config.Static()).To(Equal(defaults.Static()))
+
+			config.Workers = 2
+			Expect(config.Static()).ToNot(Equal(defaults.Static()))
+		})
+	})
+
+	Context("Watch", func() {
+		var (
+			path    string
+			stop    chan struct{}
+			lock    sync.Mutex
+			changes []*AgentConfiguration
+		)
+
+		received := func() []*AgentConfiguration {
+			lock.Lock()
+			defer lock.Unlock()
+			return append([]*AgentConfiguration{}, changes...)
+		}
+
+		BeforeEach(func() {
+			path = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+			stop = make(chan struct{})
+			changes = nil
+			DeferCleanup(func() { close(stop) })
+		})
+
+		startWatch := func() {
+			go Watch(path, defaults, defaults, 10*time.Millisecond, stop, func(config *AgentConfiguration) {
+				lock.Lock()
+				defer lock.Unlock()
+				changes = append(changes, config)
+			})
+		}
+
+		It("should call onChange with the new configuration", func() {
+			startWatch()
+			Consistently(received, 50*time.Millisecond, 10*time.Millisecond).Should(BeEmpty())
+
+			Expect(os.WriteFile(path, []byte("apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\npodSwapLimit: true\n"), 0644)).To(Succeed())
+			Eventually(received).Should(HaveLen(1))
+			Expect(received()[0].PodSwapLimit).To(BeTrue())
+		})
+
+		It("should ignore invalid configurations", func() {
+			startWatch()
+			Expect(os.WriteFile(path, []byte("workers: 0\n"), 0644)).To(Succeed())
+			Consistently(received, 50*time.Millisecond, 10*time.Millisecond).Should(BeEmpty())
+
+			Expect(os.WriteFile(path, []byte("apiVersion: wasp.io/v1alpha1\nkind: AgentConfiguration\nworkers: 3\n"), 0644)).To(Succeed())
+			Eventually(received).Should(HaveLen(1))
+			Expect(received()[0].Workers).To(Equal(3))
+		})
+	})
+})
@@ -0,0 +1,42 @@
+package agent_config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/openshift-virtualization/wasp-agent/pkg/log"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Watch polls the configuration file and calls onChange with each new valid configuration.
+// Polling is used rather than inotify since ConfigMap volumes are updated by swapping symlinks.
+// Invalid configurations are logged and ignored, the agent keeps running with the last valid one.
+func Watch(path string, defaults, running *AgentConfiguration, interval time.Duration, stop <-chan struct{}, onChange func(config *AgentConfiguration)) {
+	static := running.Static()
+	lastContent, _ := os.ReadFile(path)
+	wait.Until(func() {
+		content, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Log.Errorf("failed to read agent configuration %s: %v", path, err)
+			return
+		}
+		if bytes.Equal(content, lastContent) {
+			return
+		}
+		lastContent = content
+
+		config, err := Parse(content, defaults)
+		if err != nil {
+			log.Log.Errorf("ignoring agent configuration %s: %v", path, err)
+			return
+		}
+		if !reflect.DeepEqual(config.Static(), static) {
+			log.Log.Infof("agent configuration %s changed fields that only apply after a restart of the agent", path)
+		}
+		log.Log.Infof("reloading agent configuration %s", path)
+		onChange(config)
+	}, interval, stop)
+}
@@ -25,19 +25,96 @@ import (
 	"io"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
 	"syscall"
+	"time"
 
+	waspv1alpha1 "github.com/openshift-virtualization/wasp-agent/pkg/apis/core/v1alpha1"
 	"github.com/openshift-virtualization/wasp-agent/pkg/client"
+	"github.com/openshift-virtualization/wasp-agent/pkg/consts"
 	"github.com/openshift-virtualization/wasp-agent/pkg/informers"
 	"github.com/openshift-virtualization/wasp-agent/pkg/log"
+	"github.com/openshift-virtualization/wasp-agent/pkg/monitoring/metrics"
+	agent_config "github.com/openshift-virtualization/wasp-agent/pkg/wasp/agent-config"
+	container_runtime "github.com/openshift-virtualization/wasp-agent/pkg/wasp/container-runtime"
+	eviction_manager "github.com/openshift-virtualization/wasp-agent/pkg/wasp/eviction-manager"
 	limited_swap_manager "github.com/openshift-virtualization/wasp-agent/pkg/wasp/limited-swap-manager"
+	node_readiness "github.com/openshift-virtualization/wasp-agent/pkg/wasp/node-readiness"
+	"golang.org/x/time/rate"
+	v1 "k8s.io/api/core/v1"
+	kapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
+	kubevirtv1 "kubevirt.io/api/core/v1"
 )
 
+var (
+	swapPolicyName = flag.String("swap-policy", limited_swap_manager.DefaultSwapPolicy,
+		fmt.Sprintf("Swap allocation policy for burstable containers, one of %v", limited_swap_manager.SwapPolicyNames()))
+	fixedSwapCap            = flag.String("fixed-swap-cap", "", "Swap granted to every burstable container by the fixed-cap policy, e.g. 1Gi")
+	metricsBindAddress      = flag.String("metrics-bind-address", ":8080", "The address the metrics endpoint binds to")
+	swapUsageInterval       = flag.Duration("swap-usage-interval", 30*time.Second, "How often the swap usage of the managed containers is sampled")
+	capacityCheckInterval   = flag.Duration("capacity-check-interval", time.Minute, "How often the memory and swap of the node are read, the swap limits are recomputed when they change")
+	podSwapLimit            = flag.Bool("pod-swap-limit", false, "Also cap the swap of the pod cgroups to the swap of their containers")
+	resyncPeriod            = flag.Duration("resync-period", 10*time.Minute, "How often all the pods on the node are reconciled, on top of the reconciliation on pod changes")
+	podInformerResyncPeriod = flag.Duration("pod-informer-resync-period", time.Hour, "Resync period of the informer of the pods on the node")
+	workers                 = flag.Int("workers", 1, "The number of pods reconciled concurrently")
+	rateLimiterBaseDelay    = flag.Duration("rate-limiter-base-delay", 5*time.Millisecond, "Initial delay before retrying to reconcile a pod, doubled on each failure")
+	rateLimiterMaxDelay     = flag.Duration("rate-limiter-max-delay", 1000*time.Second, "Maximum delay before retrying to reconcile a pod")
+	rateLimiterQPS          = flag.Float64("rate-limiter-qps", 10, "Overall rate of pod reconciliation retries per second")
+	rateLimiterBurst        = flag.Int("rate-limiter-burst", 100, "Burst of pod reconciliation retries")
+	startupTaint            = flag.Bool("startup-taint", false, "Taint the node until the agent manages the swap of the pods on the node")
+	uninstall               = flag.Bool("uninstall", false, "Remove the OCI hooks and revert the swap limits of all the containers on the node, instead of managing them")
+	dryRun                  = flag.Bool("dry-run", false, "Compute and report the swap limits without changing the cgroups or installing the OCI hook")
+	configPath              = flag.String("config", "", "Path of the agent configuration file, whose fields take precedence over the flags")
+	criTimeout              = flag.Duration("cri-timeout", container_runtime.DefaultCallTimeout, "Timeout of the calls to the container runtime")
+	eviction                = flag.Bool("eviction", false, "Evict the burstable pods using the most swap when the node swap utilization or traffic exceeds its threshold")
+	evictionSwapUtilization = flag.Int("eviction-swap-utilization-threshold", 90, "Percentage of the node swap in use above which pods are evicted")
+	evictionSwapTraffic     = flag.Int64("eviction-swap-traffic-threshold", 0, "Pages swapped in and out per second on the node above which pods are evicted, 0 disables the signal")
+	evictionMinPodSwap      = flag.String("eviction-min-pod-swap", "", "Swap a pod must use to be evicted, e.g. 100Mi")
+	evictionInterval        = flag.Duration("eviction-interval", 10*time.Second, "How often the swap eviction signals of the node are sampled")
+	evictionCooldown        = flag.Duration("eviction-cooldown", 5*time.Minute, "Time after an eviction during which no other pod is evicted")
+	systemSwapReserve       = flag.String("system-swap-reserve", "", "Swap kept for the system services, the pods share the rest of the node swap, e.g. 2Gi")
+	kubepodsSwapLimit       = flag.Bool("kubepods-swap-limit", false, "Cap the swap of the kubepods cgroup to the node swap minus the system swap reserve")
+	burstableSwapLimit      = flag.Bool("burstable-swap-limit", false, "Also cap the swap of the burstable QoS cgroup to the node swap minus the system swap reserve")
+	swapHighPercent         = flag.Int("swap-high-percent", 0, "Set memory.swap.high of the containers to this percentage of their swap limit, 0 leaves it unmanaged")
+	memoryHighPercent       = flag.Int("memory-high-percent", 0, "Set memory.high of the containers between their memory request (0) and limit (100), 0 leaves it unmanaged")
+	fixedMemoryCapacity     = flag.String("fixed-memory-capacity", "", "Node memory used by the fixed memory capacity source, e.g. 60Gi")
+	memoryCapacitySource    = flag.String("memory-capacity-source", limited_swap_manager.DefaultMemoryCapacitySource,
+		fmt.Sprintf("Node memory the swap of the containers is proportional to, one of %v", limited_swap_manager.MemoryCapacitySourceNames()))
+)
+
+// configReloadInterval is how often the configuration file is checked for changes
+const configReloadInterval = 10 * time.Second
+
+// readinessRetryInterval is how often a failed update of the node readiness is retried,
+// and how often the swap capacity of the node is checked once all the pods were reconciled
+const readinessRetryInterval = 10 * time.Second
+
+const startingMessage = "the agent is reconciling the pods on the node"
+
 type WaspApp struct {
 	limitesSwapManager *limited_swap_manager.LimitedSwapManager
+	evictionManager    *eviction_manager.EvictionManager
+	readiness          *node_readiness.NodeReadiness
 	podInformer        cache.SharedIndexInformer
+	swapPolicyInformer cache.SharedIndexInformer
+	namespaceInformer  cache.SharedIndexInformer
+	vmiInformer        cache.SharedIndexInformer
+	config             *agent_config.AgentConfiguration
+	lsmConfig          limited_swap_manager.Configuration
+	memoryCapacity     limited_swap_manager.MemoryCapacitySource
+	containerRuntimes  *container_runtime.ContainerRuntimes
+	recorder           record.EventRecorder
 	ctx                context.Context
 	cli                client.WaspClient
 	waspNs             string
@@ -50,26 +127,67 @@ func Execute() {
 	flag.Parse()
 
 	var app = WaspApp{}
+	defaults, err := agentConfigurationFromFlags()
+	if err != nil {
+		panic(err)
+	}
+	if *configPath != "" {
+		app.config, err = agent_config.Load(*configPath, defaults)
+		if err != nil {
+			panic(err)
+		}
+	} else {
+		app.config = defaults
+	}
+	app.lsmConfig, err = newLimitedSwapManagerConfiguration(app.config)
+	if err != nil {
+		panic(err)
+	}
+	app.memoryCapacity, err = newMemoryCapacitySource(app.config.MemoryCapacity)
+	if err != nil {
+		panic(err)
+	}
 	app.podName, err = os.Hostname()
 	if err != nil {
 		panic(fmt.Errorf("failed to get pod name from hostname: %w", err))
 	}
 
-	setCrioSocketSymLink()
-	if err = setOCIHook(app.podName); err != nil {
+	app.containerRuntimes, err = newContainerRuntimes(app.config.ContainerRuntime)
+	if err != nil {
 		panic(err)
 	}
-	defer func() {
-		cleanupOCIHook(app.podName)
-		klog.Infof("cleanup complete, exiting")
-	}()
+	setRuntimeSocketSymLinks(app.containerRuntimes)
+	// OCI hooks are installed through the CRI-O hooks directory, on other runtimes
+	// the swap limit of the containers is only set by the LimitedSwapManager. Nothing is installed in dry-run mode
+	if app.config.DryRun {
+		log.Log.Infof("running in dry-run mode, the swap limits are only logged and exported as metrics")
+	} else if *uninstall {
+		cleanupAllOCIHooks(app.config.Hooks.ScriptDir, app.config.Hooks.ConfigDir)
+	} else if slices.Contains(app.containerRuntimes.Names(), container_runtime.CrioName) {
+		hooks := app.config.Hooks
+		if err = setOCIHook(hooks.ScriptDir, hooks.ConfigDir, app.podName); err != nil {
+			panic(err)
+		}
+		defer func() {
+			cleanupOCIHook(hooks.ScriptDir, hooks.ConfigDir, app.podName)
+			klog.Infof("cleanup complete, exiting")
+		}()
+	}
 
 	app.nodeName = os.Getenv("NODE_NAME")
+	if app.nodeName == "" {
+		panic("NODE_NAME environment variable is not set")
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	app.ctx = ctx
 
+	if err = metrics.SetupMetrics(); err != nil {
+		panic(err)
+	}
+	go serveMetrics(ctx, *metricsBindAddress)
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
 	go func() {
@@ -87,54 +205,450 @@ func Execute() {
 	if err != nil {
 		panic(err)
 	}
-	app.podInformer = informers.GetPodInformer(app.cli)
+	app.podInformer = informers.GetPodInformer(app.cli, app.nodeName, app.config.PodInformerResyncPeriod.Duration)
+	if app.swapPolicyCRDInstalled() {
+		app.swapPolicyInformer = informers.GetSwapPolicyInformer(app.cli)
+		app.namespaceInformer = informers.GetNamespaceInformer(app.cli)
+	} else {
+		log.Log.Infof("SwapPolicy CRD is not installed, applying swap policy %v to all pods", app.lsmConfig.SwapPolicy.Name())
+	}
+	if app.kubevirtInstalled() {
+		app.vmiInformer = informers.GetVMIInformer(app.cli, app.nodeName)
+	} else {
+		log.Log.Infof("KubeVirt is not installed, virt-launcher pods are not handled")
+	}
+	app.recorder = newEventRecorder(app.cli, app.nodeName)
+	// the startup taint is left alone when the agent does not manage swap
+	manageTaint := app.config.StartupTaint && !app.config.DryRun && !*uninstall
+	app.readiness = node_readiness.New(app.cli.CoreV1().Nodes(), app.nodeName, manageTaint)
 
 	log.Log.Infof("nodeName: %v "+
 		"ns: %v "+
+		"containerRuntime: %v "+
+		"swapPolicy: %v "+
+		"memoryCapacitySource: %v",
 		app.nodeName,
 		app.waspNs,
+		strings.Join(app.containerRuntimes.Names(), ","),
+		app.lsmConfig.SwapPolicy.Name(),
+		app.memoryCapacity.Name(),
 	)
 
 	stop := ctx.Done()
-	app.initLimitedSwapManager(stop)
+	// the OCI hook is removed on return, so that containers do not get unlimited swap while the agent can not limit it
+	if err = app.initLimitedSwapManager(stop); err != nil {
+		log.Log.Errorf("failed to start the LimitedSwapManager: %v", err)
+		return
+	}
+	if *uninstall {
+		app.Uninstall(stop)
+		return
+	}
+	if app.config.Eviction.Enabled {
+		app.initEvictionManager()
+	}
+	if *configPath != "" {
+		go agent_config.Watch(*configPath, defaults, app.config, configReloadInterval, stop, app.reloadConfiguration)
+	}
 	app.Run(stop)
 }
 
-func (waspapp *WaspApp) initLimitedSwapManager(stop <-chan struct{}) {
-	waspapp.limitesSwapManager = limited_swap_manager.NewLimitedSwapManager(waspapp.cli,
+func (waspapp *WaspApp) initLimitedSwapManager(stop <-chan struct{}) error {
+	var err error
+	waspapp.limitesSwapManager, err = limited_swap_manager.NewLimitedSwapManager(waspapp.cli,
 		waspapp.podInformer,
+		waspapp.swapPolicyInformer,
+		waspapp.namespaceInformer,
+		waspapp.vmiInformer,
 		waspapp.nodeName,
+		waspapp.lsmConfig,
+		waspapp.memoryCapacity,
+		limited_swap_manager.KubepodsSwapLimit{
+			SystemReserve: waspapp.config.SystemSwapReserve.Value(),
+			Kubepods:      waspapp.config.KubepodsSwapLimit,
+			Burstable:     waspapp.config.BurstableSwapLimit,
+		},
+		waspapp.config.CgroupRoot,
+		waspapp.config.DryRun,
+		waspapp.recorder,
+		waspapp.containerRuntimes,
+		waspapp.config.SwapUsageInterval.Duration,
+		waspapp.config.CapacityCheckInterval.Duration,
+		waspapp.config.ResyncPeriod.Duration,
+		newRateLimiter(waspapp.config.RateLimiter),
 		stop,
 	)
+	return err
 }
 
-func (waspapp *WaspApp) Run(stop <-chan struct{}) {
-	go waspapp.podInformer.Run(stop)
+func (waspapp *WaspApp) initEvictionManager() {
+	waspapp.evictionManager = eviction_manager.New(waspapp.cli.CoreV1(),
+		waspapp.podInformer,
+		waspapp.limitesSwapManager,
+		waspapp.recorder,
+		newEvictionConfiguration(waspapp.config.Eviction),
+		waspapp.config.DryRun,
+	)
+}
 
-	if !cache.WaitForCacheSync(stop,
-		waspapp.podInformer.HasSynced,
-	) {
-		klog.Warningf("failed to wait for caches to sync")
+func newEvictionConfiguration(config agent_config.EvictionConfiguration) eviction_manager.Configuration {
+	return eviction_manager.Configuration{
+		SwapUtilizationThreshold: float64(config.SwapUtilizationThresholdPercent) / 100,
+		SwapTrafficThreshold:     config.SwapTrafficThreshold,
+		MinPodSwap:               config.MinPodSwap.Value(),
+		Interval:                 config.Interval.Duration,
+		Cooldown:                 config.Cooldown.Duration,
+	}
+}
+
+func (waspapp *WaspApp) swapPolicyCRDInstalled() bool {
+	resources, err := waspapp.cli.DiscoveryClient().ServerResourcesForGroupVersion(waspv1alpha1.SchemeGroupVersion.String())
+	if kapierrors.IsNotFound(err) {
+		return false
+	} else if err != nil {
+		panic(err)
+	}
+	for _, apiResource := range resources.APIResources {
+		if apiResource.Name == "swappolicies" {
+			return true
+		}
+	}
+	return false
+}
+
+func (waspapp *WaspApp) kubevirtInstalled() bool {
+	resources, err := waspapp.cli.DiscoveryClient().ServerResourcesForGroupVersion(kubevirtv1.GroupVersion.String())
+	if kapierrors.IsNotFound(err) {
+		return false
+	} else if err != nil {
+		panic(err)
+	}
+	for _, apiResource := range resources.APIResources {
+		if apiResource.Name == "virtualmachineinstances" {
+			return true
+		}
 	}
+	return false
+}
+
+func newEventRecorder(cli client.WaspClient, nodeName string) record.EventRecorder {
+	eventBroadcaster := record.NewBroadcaster()
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: cli.CoreV1().Events(v1.NamespaceAll)})
+	return eventBroadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: "wasp-agent", Host: nodeName})
+}
+
+// newRateLimiter returns the rate limiter of the pod queue, the defaults are the ones of workqueue.DefaultControllerRateLimiter
+func newRateLimiter(config agent_config.RateLimiterConfiguration) workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(config.BaseDelay.Duration, config.MaxDelay.Duration),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(config.QPS), config.Burst)},
+	)
+}
+
+// reloadConfiguration applies the fields of a new configuration that can be changed at runtime
+func (waspapp *WaspApp) reloadConfiguration(config *agent_config.AgentConfiguration) {
+	lsmConfig, err := newLimitedSwapManagerConfiguration(config)
+	if err != nil {
+		log.Log.Errorf("ignoring agent configuration: %v", err)
+		return
+	}
+	waspapp.limitesSwapManager.SetConfiguration(lsmConfig)
+}
+
+// newLimitedSwapManagerConfiguration returns the part of the agent configuration that can be changed at runtime
+func newLimitedSwapManagerConfiguration(config *agent_config.AgentConfiguration) (limited_swap_manager.Configuration, error) {
+	swapPolicy, err := newSwapPolicy(config.SwapPolicy)
+	if err != nil {
+		return limited_swap_manager.Configuration{}, err
+	}
+	inclusions, err := newPodRules(config.Inclusions)
+	if err != nil {
+		return limited_swap_manager.Configuration{}, err
+	}
+	exclusions, err := newPodRules(config.Exclusions)
+	if err != nil {
+		return limited_swap_manager.Configuration{}, err
+	}
+	return limited_swap_manager.Configuration{
+		SwapPolicy:   swapPolicy,
+		PodSwapLimit: config.PodSwapLimit,
+		Inclusions:   inclusions,
+		Exclusions:   exclusions,
+		Throttling: limited_swap_manager.Throttling{
+			SwapHighPercent:   config.SwapPolicy.SwapHighPercent,
+			MemoryHighPercent: config.SwapPolicy.MemoryHighPercent,
+		},
+	}, nil
+}
+
+func newPodRules(config agent_config.PodRulesConfiguration) (limited_swap_manager.PodRules, error) {
+	rules := limited_swap_manager.PodRules{
+		Namespaces:         config.Namespaces,
+		PriorityClassNames: config.PriorityClassNames,
+	}
+	if config.PodSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(config.PodSelector)
+		if err != nil {
+			return rules, err
+		}
+		rules.PodSelector = selector
+	}
+	return rules, nil
+}
+
+// agentConfigurationFromFlags returns the configuration set by the command line flags,
+// the fields of the configuration file are applied on top of it
+func agentConfigurationFromFlags() (*agent_config.AgentConfiguration, error) {
+	config := &agent_config.AgentConfiguration{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: agent_config.APIVersion,
+			Kind:       agent_config.Kind,
+		},
+		SwapPolicy: agent_config.SwapPolicyConfiguration{
+			Name:              *swapPolicyName,
+			SwapHighPercent:   *swapHighPercent,
+			MemoryHighPercent: *memoryHighPercent,
+		},
+		MemoryCapacity:          agent_config.MemoryCapacityConfiguration{Source: *memoryCapacitySource},
+		KubepodsSwapLimit:       *kubepodsSwapLimit,
+		BurstableSwapLimit:      *burstableSwapLimit,
+		PodSwapLimit:            *podSwapLimit,
+		ResyncPeriod:            metav1.Duration{Duration: *resyncPeriod},
+		PodInformerResyncPeriod: metav1.Duration{Duration: *podInformerResyncPeriod},
+		SwapUsageInterval:       metav1.Duration{Duration: *swapUsageInterval},
+		CapacityCheckInterval:   metav1.Duration{Duration: *capacityCheckInterval},
+		Workers:                 *workers,
+		ContainerRuntime:        agent_config.ContainerRuntimeConfiguration{Timeout: metav1.Duration{Duration: *criTimeout}},
+		CgroupRoot:              limited_swap_manager.DefaultCgroupRoot,
+		DryRun:                  *dryRun,
+		StartupTaint:            *startupTaint,
+		RateLimiter: agent_config.RateLimiterConfiguration{
+			BaseDelay: metav1.Duration{Duration: *rateLimiterBaseDelay},
+			MaxDelay:  metav1.Duration{Duration: *rateLimiterMaxDelay},
+			QPS:       *rateLimiterQPS,
+			Burst:     *rateLimiterBurst,
+		},
+		Hooks: agent_config.HooksConfiguration{
+			ScriptDir: consts.HookScriptDir,
+			ConfigDir: consts.HookConfigDir,
+		},
+		Eviction: agent_config.EvictionConfiguration{
+			Enabled:                         *eviction,
+			SwapUtilizationThresholdPercent: *evictionSwapUtilization,
+			SwapTrafficThreshold:            *evictionSwapTraffic,
+			Interval:                        metav1.Duration{Duration: *evictionInterval},
+			Cooldown:                        metav1.Duration{Duration: *evictionCooldown},
+		},
+	}
+	if *evictionMinPodSwap != "" {
+		quantity, err := resource.ParseQuantity(*evictionMinPodSwap)
+		if err != nil {
+			return nil, fmt.Errorf("invalid eviction min pod swap %q: %w", *evictionMinPodSwap, err)
+		}
+		config.Eviction.MinPodSwap = quantity
+	}
+	if *systemSwapReserve != "" {
+		quantity, err := resource.ParseQuantity(*systemSwapReserve)
+		if err != nil {
+			return nil, fmt.Errorf("invalid system swap reserve %q: %w", *systemSwapReserve, err)
+		}
+		config.SystemSwapReserve = quantity
+	}
+	if *fixedMemoryCapacity != "" {
+		quantity, err := resource.ParseQuantity(*fixedMemoryCapacity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fixed memory capacity %q: %w", *fixedMemoryCapacity, err)
+		}
+		config.MemoryCapacity.Fixed = &quantity
+	}
+	if *fixedSwapCap != "" {
+		quantity, err := resource.ParseQuantity(*fixedSwapCap)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fixed swap cap %q: %w", *fixedSwapCap, err)
+		}
+		config.SwapPolicy.FixedCap = &quantity
+	}
+	return config, config.Validate()
+}
+
+func newSwapPolicy(config agent_config.SwapPolicyConfiguration) (limited_swap_manager.SwapPolicy, error) {
+	opts := limited_swap_manager.SwapPolicyOptions{}
+	if config.FixedCap != nil {
+		opts.FixedCap = *config.FixedCap
+	}
+	return limited_swap_manager.NewSwapPolicy(config.Name, opts)
+}
+
+func newMemoryCapacitySource(config agent_config.MemoryCapacityConfiguration) (limited_swap_manager.MemoryCapacitySource, error) {
+	fixed := resource.Quantity{}
+	if config.Fixed != nil {
+		fixed = *config.Fixed
+	}
+	return limited_swap_manager.NewMemoryCapacitySource(config.Source, fixed)
+}
+
+// newContainerRuntimes returns the configured container runtime, or the runtimes detected on the node
+func newContainerRuntimes(config agent_config.ContainerRuntimeConfiguration) (*container_runtime.ContainerRuntimes, error) {
+	if config.Name != "" {
+		runtime, err := container_runtime.NewByName(config.Name, config.SocketPath, config.Timeout.Duration)
+		if err != nil {
+			return nil, err
+		}
+		return container_runtime.NewContainerRuntimes(runtime), nil
+	}
+	return container_runtime.New(config.Timeout.Duration).Detect()
+}
+
+// Uninstall reverts the swap limits of all the containers on the node, then waits for the agent to be deleted
+// so that the DaemonSet does not restart it
+func (waspapp *WaspApp) Uninstall(stop <-chan struct{}) {
+	defer waspapp.containerRuntimes.Close()
+	go waspapp.updateReadiness(func() error {
+		return waspapp.readiness.SetUninstalled("the agent is uninstalled")
+	})
+	waspapp.startInformers(stop)
+	restored, err := waspapp.limitesSwapManager.RestoreSwapLimits()
+	if err != nil {
+		log.Log.Errorf("failed to revert some swap limits: %v", err)
+	}
+	log.Log.Infof("uninstall complete, reverted the swap limit of %d containers", restored)
+	<-waspapp.ctx.Done()
+}
+
+func (waspapp *WaspApp) Run(stop <-chan struct{}) {
+	waspapp.publishStarting()
+	readinessDone := make(chan struct{})
 	go func() {
-		waspapp.limitesSwapManager.Run(1)
+		defer close(readinessDone)
+		waspapp.publishReadiness()
 	}()
+	waspapp.startInformers(stop)
+	go func() {
+		waspapp.limitesSwapManager.Run(waspapp.config.Workers)
+	}()
+	if waspapp.evictionManager != nil {
+		go waspapp.evictionManager.Run(stop)
+	}
 
 	<-waspapp.ctx.Done()
+	<-readinessDone
+	waspapp.publishStopping()
+}
 
+// publishStarting taints the node before the pods are reconciled on every start of the agent, since the
+// condition and the taint may have been left as they were by an agent pod that did not shut down cleanly.
+// Failures are retried by publishReadiness, so that they do not delay the reconciliation of the pods.
+func (waspapp *WaspApp) publishStarting() {
+	if waspapp.config.DryRun {
+		return
+	}
+	if err := waspapp.readiness.SetNotReady(node_readiness.ReasonStarting, startingMessage); err != nil {
+		log.Log.Errorf("%v", err)
+	}
 }
 
-func setCrioSocketSymLink() {
-	err := os.MkdirAll("/var/run/crio", 0755)
-	if err != nil {
-		klog.Warningf(err.Error())
+// publishStopping sets the condition to false when the agent stops, since the swap of the containers
+// is not managed until the agent runs again. The node is not tainted, nothing would remove the taint
+// when the DaemonSet is deleted, the next start of the agent taints it instead.
+func (waspapp *WaspApp) publishStopping() {
+	if waspapp.config.DryRun {
 		return
 	}
-	os.Symlink("/host/var/run/crio/crio.sock", "/var/run/crio/crio.sock")
-	if err != nil {
-		klog.Warningf(err.Error())
+	if err := waspapp.readiness.SetStopped(node_readiness.ReasonStopping, "the agent is not running and does not manage swap"); err != nil {
+		log.Log.Errorf("%v", err)
+	}
+}
+
+// publishReadiness sets the WaspReady condition of the node once the OCI hook is installed, swap is detected
+// and all the pods on the node were reconciled once. The condition then follows the swap capacity of the node.
+func (waspapp *WaspApp) publishReadiness() {
+	if waspapp.config.DryRun {
+		waspapp.updateReadiness(func() error {
+			return waspapp.readiness.SetNotReady(node_readiness.ReasonDryRun, "the agent runs in dry-run mode and does not manage swap")
+		})
 		return
 	}
+	waspapp.updateReadiness(func() error {
+		return waspapp.readiness.SetNotReady(node_readiness.ReasonStarting, startingMessage)
+	})
+	select {
+	case <-waspapp.limitesSwapManager.InitialSyncDone():
+	case <-waspapp.ctx.Done():
+		return
+	}
+	var hadSwap *bool
+	var lastFailures int
+	wait.Until(func() {
+		hasSwap := waspapp.limitesSwapManager.SwapCapacity() > 0
+		failures := waspapp.limitesSwapManager.InitialSyncFailures()
+		if hadSwap != nil && *hadSwap == hasSwap && (!hasSwap || lastFailures == failures) {
+			return
+		}
+		hadSwap = &hasSwap
+		if !hasSwap {
+			waspapp.updateReadiness(func() error {
+				return waspapp.readiness.SetNotReady(node_readiness.ReasonNoSwap, "no swap is available to the pods on the node")
+			})
+			return
+		}
+		lastFailures = failures
+		waspapp.updateReadiness(func() error {
+			return waspapp.readiness.SetReady(readyMessage(failures))
+		})
+	}, readinessRetryInterval, waspapp.ctx.Done())
+}
+
+// readyMessage is the message of the WaspReady condition, it reports the pods found at startup whose swap limits
+// could not be set, they no longer delay the readiness of the node but their reconciliation is still retried
+func readyMessage(failures int) string {
+	if failures == 0 {
+		return "the agent manages the swap of the pods on the node"
+	}
+	return fmt.Sprintf("the agent manages the swap of the pods on the node, the swap limits of %d pods found at startup could not be set yet", failures)
+}
+
+// updateReadiness retries an update of the node readiness until it succeeds or the agent stops
+func (waspapp *WaspApp) updateReadiness(update func() error) {
+	_ = wait.PollUntilContextCancel(waspapp.ctx, readinessRetryInterval, true, func(context.Context) (bool, error) {
+		if err := update(); err != nil {
+			log.Log.Errorf("%v", err)
+			return false, nil
+		}
+		return true, nil
+	})
+}
+
+func (waspapp *WaspApp) startInformers(stop <-chan struct{}) {
+	go waspapp.podInformer.Run(stop)
+	cacheSyncs := []cache.InformerSynced{waspapp.podInformer.HasSynced}
+	if waspapp.swapPolicyInformer != nil {
+		go waspapp.swapPolicyInformer.Run(stop)
+		go waspapp.namespaceInformer.Run(stop)
+		cacheSyncs = append(cacheSyncs, waspapp.swapPolicyInformer.HasSynced, waspapp.namespaceInformer.HasSynced)
+	}
+	if waspapp.vmiInformer != nil {
+		go waspapp.vmiInformer.Run(stop)
+		cacheSyncs = append(cacheSyncs, waspapp.vmiInformer.HasSynced)
+	}
+
+	if !cache.WaitForCacheSync(stop, cacheSyncs...) {
+		klog.Warningf("failed to wait for caches to sync")
+	}
+}
+
+// setRuntimeSocketSymLinks links the CRI socket of every runtime of the node to its path on the host
+func setRuntimeSocketSymLinks(containerRuntimes *container_runtime.ContainerRuntimes) {
+	for _, runtime := range containerRuntimes.Runtimes() {
+		err := os.MkdirAll(filepath.Dir(runtime.SocketPath()), 0755)
+		if err != nil {
+			klog.Warning(err.Error())
+			continue
+		}
+		err = os.Symlink(containerRuntimes.HostSocketPath(runtime), runtime.SocketPath())
+		if err != nil {
+			klog.Warning(err.Error())
+		}
+	}
 }
 
 func moveFile(sourcePath, destPath string) error {
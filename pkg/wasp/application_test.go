@@ -16,10 +16,39 @@ limitations under the License.
 package wasp
 
 import (
+	"context"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+
+	agent_config "github.com/openshift-virtualization/wasp-agent/pkg/wasp/agent-config"
+	node_readiness "github.com/openshift-virtualization/wasp-agent/pkg/wasp/node-readiness"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 )
 
+// fakeNodes stores a single node and counts its updates
+type fakeNodes struct {
+	corev1client.NodeInterface
+	node    *v1.Node
+	updates int
+}
+
+func (f *fakeNodes) Get(_ context.Context, _ string, _ metav1.GetOptions) (*v1.Node, error) {
+	return f.node.DeepCopy(), nil
+}
+
+func (f *fakeNodes) Update(_ context.Context, node *v1.Node, _ metav1.UpdateOptions) (*v1.Node, error) {
+	f.updates++
+	f.node = node.DeepCopy()
+	return node, nil
+}
+
+func (f *fakeNodes) UpdateStatus(ctx context.Context, node *v1.Node, opts metav1.UpdateOptions) (*v1.Node, error) {
+	return f.Update(ctx, node, opts)
+}
+
 var _ = Describe("Controller tests", func() {
 	Context("Controller", func() {
 		It("first fake unit test", func() {
@@ -27,3 +56,45 @@ var _ = Describe("Controller tests", func() {
 		})
 	})
 })
+
+var _ = Describe("Node readiness of the agent", func() {
+	var nodes *fakeNodes
+
+	BeforeEach(func() {
+		nodes = &fakeNodes{node: &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node01"}}}
+	})
+
+	newApp := func(dryRun bool) *WaspApp {
+		return &WaspApp{
+			config:    &agent_config.AgentConfiguration{DryRun: dryRun, StartupTaint: true},
+			readiness: node_readiness.New(nodes, "node01", !dryRun),
+		}
+	}
+
+	It("should taint the node when it starts but not when it stops", func() {
+		app := newApp(false)
+		app.publishStopping()
+		Expect(nodes.node.Spec.Taints).To(BeEmpty())
+		Expect(nodes.node.Status.Conditions).To(ConsistOf(HaveField("Reason", node_readiness.ReasonStopping)))
+
+		app.publishStarting()
+		Expect(nodes.node.Spec.Taints).To(ConsistOf(HaveField("Key", node_readiness.StartupTaintKey)))
+		app.publishStopping()
+		Expect(nodes.node.Spec.Taints).To(ConsistOf(HaveField("Key", node_readiness.StartupTaintKey)))
+		Expect(nodes.node.Status.Conditions).To(ConsistOf(HaveField("Reason", node_readiness.ReasonStopping)))
+	})
+
+	It("should not update the node when it starts or stops in dry-run mode", func() {
+		app := newApp(true)
+		app.publishStarting()
+		app.publishStopping()
+		Expect(nodes.updates).To(BeZero())
+	})
+})
+
+var _ = Describe("Ready message", func() {
+	It("should report the pods that could not be reconciled at startup", func() {
+		Expect(readyMessage(0)).To(Equal("the agent manages the swap of the pods on the node"))
+		Expect(readyMessage(2)).To(ContainSubstring("the swap limits of 2 pods found at startup could not be set yet"))
+	})
+})
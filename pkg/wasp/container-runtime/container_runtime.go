@@ -0,0 +1,141 @@
+package container_runtime
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openshift-virtualization/wasp-agent/pkg/log"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+const hostRoot = "/host"
+
+// ContainerRuntime is a CRI runtime running the containers of the node
+type ContainerRuntime interface {
+	Name() string
+	// ContainerIDScheme is the scheme of the container IDs reported in the pod status, e.g. cri-o
+	ContainerIDScheme() string
+	// SocketPath is the path of the CRI socket on the node
+	SocketPath() string
+	ContainerStatus(containerID string) (*runtimeapi.ContainerStatusResponse, error)
+	// ContainerPid returns the PID of the container init process
+	ContainerPid(containerID string) (int, error)
+	// ContainerCgroupNames returns the possible names of the container cgroup in the pod cgroup,
+	// they depend on the runtime and on the cgroup driver
+	ContainerCgroupNames(containerID string) []string
+	// HealthCheck checks the runtime can be reached, it is a no-op until the runtime was called once
+	HealthCheck() error
+	Close() error
+}
+
+// ContainerRuntimes resolves the runtime of a container from its container ID scheme,
+// so the same agent works on nodes running different runtimes
+type ContainerRuntimes struct {
+	runtimes map[string]ContainerRuntime
+	hostRoot string
+}
+
+// New returns the supported container runtimes, CRI calls time out after callTimeout
+func New(callTimeout time.Duration) *ContainerRuntimes {
+	return NewContainerRuntimes(NewCrio(CrioSocketPath, callTimeout), NewContainerd(ContainerdSocketPath, callTimeout))
+}
+
+// NewByName returns a runtime by name, listening on socketPath or on the default socket of the runtime when empty
+func NewByName(name, socketPath string, callTimeout time.Duration) (ContainerRuntime, error) {
+	switch name {
+	case CrioName:
+		if socketPath == "" {
+			socketPath = CrioSocketPath
+		}
+		return NewCrio(socketPath, callTimeout), nil
+	case ContainerdName:
+		if socketPath == "" {
+			socketPath = ContainerdSocketPath
+		}
+		return NewContainerd(socketPath, callTimeout), nil
+	default:
+		return nil, fmt.Errorf("unsupported container runtime %q, must be one of %s, %s", name, CrioName, ContainerdName)
+	}
+}
+
+func NewContainerRuntimes(runtimes ...ContainerRuntime) *ContainerRuntimes {
+	containerRuntimes := &ContainerRuntimes{runtimes: map[string]ContainerRuntime{}, hostRoot: hostRoot}
+	for _, runtime := range runtimes {
+		containerRuntimes.runtimes[runtime.ContainerIDScheme()] = runtime
+	}
+	return containerRuntimes
+}
+
+// ForContainerID returns the runtime of a container ID as reported in the pod status,
+// e.g. cri-o://<id>, and the container ID without its scheme
+func (c *ContainerRuntimes) ForContainerID(containerID string) (ContainerRuntime, string, error) {
+	scheme, id, found := strings.Cut(containerID, "://")
+	if !found || id == "" {
+		return nil, "", fmt.Errorf("malformed container ID %q", containerID)
+	}
+	runtime, ok := c.runtimes[scheme]
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported container runtime %q", scheme)
+	}
+	return runtime, id, nil
+}
+
+// Detect returns the runtimes whose CRI socket exists on the node. Nodes may run more than one runtime,
+// the runtime of a container is then resolved from its container ID scheme.
+func (c *ContainerRuntimes) Detect() (*ContainerRuntimes, error) {
+	detected := &ContainerRuntimes{runtimes: map[string]ContainerRuntime{}, hostRoot: c.hostRoot}
+	for scheme, runtime := range c.runtimes {
+		if _, err := os.Stat(c.HostSocketPath(runtime)); err == nil {
+			detected.runtimes[scheme] = runtime
+		}
+	}
+	if len(detected.runtimes) == 0 {
+		return nil, fmt.Errorf("no container runtime socket found")
+	}
+	return detected, nil
+}
+
+// Runtimes returns the runtimes sorted by name
+func (c *ContainerRuntimes) Runtimes() []ContainerRuntime {
+	runtimes := make([]ContainerRuntime, 0, len(c.runtimes))
+	for _, runtime := range c.runtimes {
+		runtimes = append(runtimes, runtime)
+	}
+	sort.Slice(runtimes, func(i, j int) bool {
+		return runtimes[i].Name() < runtimes[j].Name()
+	})
+	return runtimes
+}
+
+// Names returns the names of the runtimes, sorted
+func (c *ContainerRuntimes) Names() []string {
+	var names []string
+	for _, runtime := range c.Runtimes() {
+		names = append(names, runtime.Name())
+	}
+	return names
+}
+
+// HealthCheck checks the runtimes in use can be reached
+func (c *ContainerRuntimes) HealthCheck() {
+	for _, runtime := range c.runtimes {
+		_ = runtime.HealthCheck()
+	}
+}
+
+func (c *ContainerRuntimes) Close() {
+	for _, runtime := range c.runtimes {
+		if err := runtime.Close(); err != nil {
+			log.Log.Infof("failed to close the connection to container runtime %s: %v", runtime.Name(), err)
+		}
+	}
+}
+
+// HostSocketPath is the path of the CRI socket of the runtime as mounted in the agent
+func (c *ContainerRuntimes) HostSocketPath(runtime ContainerRuntime) string {
+	return filepath.Join(c.hostRoot, runtime.SocketPath())
+}
@@ -0,0 +1,87 @@
+package container_runtime
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Container runtimes", func() {
+	var containerRuntimes *ContainerRuntimes
+
+	BeforeEach(func() {
+		containerRuntimes = New(DefaultCallTimeout)
+		containerRuntimes.hostRoot = GinkgoT().TempDir()
+	})
+
+	createSocket := func(runtime ContainerRuntime) {
+		socketPath := containerRuntimes.HostSocketPath(runtime)
+		Expect(os.MkdirAll(filepath.Dir(socketPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(socketPath, nil, 0600)).To(Succeed())
+	}
+
+	DescribeTable("should resolve the runtime from the container ID scheme", func(containerID, runtimeName, id string) {
+		runtime, trimmedID, err := containerRuntimes.ForContainerID(containerID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(runtime.Name()).To(Equal(runtimeName))
+		Expect(trimmedID).To(Equal(id))
+	},
+		Entry("cri-o", "cri-o://0123abcd", CrioName, "0123abcd"),
+		Entry("containerd", "containerd://4567ef01", ContainerdName, "4567ef01"),
+	)
+
+	DescribeTable("should reject container IDs", func(containerID string) {
+		_, _, err := containerRuntimes.ForContainerID(containerID)
+		Expect(err).To(HaveOccurred())
+	},
+		Entry("without scheme", "0123abcd"),
+		Entry("without ID", "cri-o://"),
+		Entry("of unsupported runtimes", "docker://0123abcd"),
+	)
+
+	It("should create a runtime by name with a custom socket", func() {
+		runtime, err := NewByName(ContainerdName, "/run/k3s/containerd/containerd.sock", DefaultCallTimeout)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(runtime.Name()).To(Equal(ContainerdName))
+		Expect(runtime.SocketPath()).To(Equal("/run/k3s/containerd/containerd.sock"))
+
+		runtime, err = NewByName(CrioName, "", DefaultCallTimeout)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(runtime.SocketPath()).To(Equal(CrioSocketPath))
+
+		_, err = NewByName("docker", "", DefaultCallTimeout)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should detect the runtime from its socket", func() {
+		createSocket(NewContainerd(ContainerdSocketPath, DefaultCallTimeout))
+		detected, err := containerRuntimes.Detect()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(detected.Names()).To(Equal([]string{ContainerdName}))
+
+		_, _, err = detected.ForContainerID("cri-o://0123abcd")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should fail to detect the runtime without socket", func() {
+		_, err := containerRuntimes.Detect()
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should detect all the runtimes when more than one socket exists", func() {
+		createSocket(NewCrio(CrioSocketPath, DefaultCallTimeout))
+		createSocket(NewContainerd(ContainerdSocketPath, DefaultCallTimeout))
+		detected, err := containerRuntimes.Detect()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(detected.Names()).To(Equal([]string{ContainerdName, CrioName}))
+
+		runtime, _, err := detected.ForContainerID("cri-o://0123abcd")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(runtime.Name()).To(Equal(CrioName))
+		runtime, _, err = detected.ForContainerID("containerd://4567ef01")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(runtime.Name()).To(Equal(ContainerdName))
+	})
+})
@@ -0,0 +1,13 @@
+package container_runtime
+
+import "time"
+
+const (
+	ContainerdName       = "containerd"
+	ContainerdSocketPath = "/run/containerd/containerd.sock"
+)
+
+// NewContainerd returns the containerd runtime listening on socketPath
+func NewContainerd(socketPath string, callTimeout time.Duration) ContainerRuntime {
+	return newCriRuntime(ContainerdName, "containerd", socketPath, "cri-containerd", callTimeout)
+}
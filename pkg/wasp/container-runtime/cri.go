@@ -0,0 +1,190 @@
+package container_runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/openshift-virtualization/wasp-agent/pkg/log"
+	"github.com/openshift-virtualization/wasp-agent/pkg/monitoring/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+const (
+	DefaultCallTimeout = 5 * time.Second
+	maxReconnectDelay  = 30 * time.Second
+)
+
+// criRuntime talks to a runtime implementing the CRI runtime service.
+// The gRPC connection is opened on the first call and kept for the lifetime of the agent,
+// gRPC reconnects it with an exponential backoff when the runtime goes away.
+type criRuntime struct {
+	name        string
+	scheme      string
+	socketPath  string
+	callTimeout time.Duration
+	// cgroupPrefix prefixes the container ID in the name of the container cgroup
+	cgroupPrefix string
+
+	lock   sync.Mutex
+	conn   *grpc.ClientConn
+	client runtimeapi.RuntimeServiceClient
+	// reachable is nil until the runtime was called once
+	reachable *bool
+}
+
+func newCriRuntime(name, scheme, socketPath, cgroupPrefix string, callTimeout time.Duration) *criRuntime {
+	return &criRuntime{
+		name:         name,
+		scheme:       scheme,
+		socketPath:   socketPath,
+		cgroupPrefix: cgroupPrefix,
+		callTimeout:  callTimeout,
+	}
+}
+
+func (c *criRuntime) Name() string {
+	return c.name
+}
+
+func (c *criRuntime) ContainerIDScheme() string {
+	return c.scheme
+}
+
+func (c *criRuntime) SocketPath() string {
+	return c.socketPath
+}
+
+// ContainerCgroupNames returns the systemd scope of the container, then its cgroupfs directory
+func (c *criRuntime) ContainerCgroupNames(containerID string) []string {
+	return []string{
+		fmt.Sprintf("%s-%s.scope", c.cgroupPrefix, containerID),
+		fmt.Sprintf("%s-%s", c.cgroupPrefix, containerID),
+		containerID,
+	}
+}
+
+func (c *criRuntime) runtimeClient() (runtimeapi.RuntimeServiceClient, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.client != nil {
+		return c.client, nil
+	}
+
+	backoffConfig := backoff.DefaultConfig
+	backoffConfig.MaxDelay = maxReconnectDelay
+	conn, err := grpc.Dial("unix://"+c.socketPath,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: backoffConfig, MinConnectTimeout: c.callTimeout}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+	c.client = runtimeapi.NewRuntimeServiceClient(conn)
+	return c.client, nil
+}
+
+func (c *criRuntime) ContainerStatus(containerID string) (*runtimeapi.ContainerStatusResponse, error) {
+	client, err := c.runtimeClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.callTimeout)
+	defer cancel()
+	request := &runtimeapi.ContainerStatusRequest{ContainerId: containerID, Verbose: true}
+	response, err := client.ContainerStatus(ctx, request)
+	c.observe(err)
+	return response, err
+}
+
+type containerInfo struct {
+	Pid int `json:"pid"`
+}
+
+// ContainerPid reads the PID from the verbose container info,
+// both CRI-O and containerd report it as the pid field of the info key
+func (c *criRuntime) ContainerPid(containerID string) (int, error) {
+	containerStatusResponse, err := c.ContainerStatus(containerID)
+	if err != nil {
+		return 0, err
+	}
+	if containerStatusResponse.Info == nil {
+		return 0, fmt.Errorf("Failed to get container status info")
+	}
+
+	var info containerInfo
+	err = json.Unmarshal([]byte(containerStatusResponse.Info["info"]), &info)
+	if err != nil {
+		return 0, err
+	}
+	if info.Pid == 0 {
+		return 0, fmt.Errorf("PID not found in container info")
+	}
+	return info.Pid, nil
+}
+
+// HealthCheck asks the runtime for its version, runtimes that were never called are skipped. A connection in backoff is woken up
+// so a runtime coming back is noticed without waiting for the next reconnection attempt.
+func (c *criRuntime) HealthCheck() error {
+	c.lock.Lock()
+	client := c.client
+	if client == nil {
+		c.lock.Unlock()
+		return nil
+	}
+	if state := c.conn.GetState(); state == connectivity.Idle || state == connectivity.TransientFailure {
+		c.conn.Connect()
+	}
+	c.lock.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.callTimeout)
+	defer cancel()
+	_, err := client.Version(ctx, &runtimeapi.VersionRequest{})
+	c.observe(err)
+	return err
+}
+
+func (c *criRuntime) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	c.client = nil
+	return err
+}
+
+// observe records whether the runtime could be reached by a call,
+// errors returned by the runtime itself, e.g. NotFound, mean it is reachable
+func (c *criRuntime) observe(err error) {
+	code := status.Code(err)
+	reachable := code != codes.Unavailable && code != codes.DeadlineExceeded
+	if !reachable {
+		metrics.IncContainerRuntimeError(c.name)
+	}
+	metrics.SetContainerRuntimeUp(c.name, reachable)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.reachable != nil && *c.reachable == reachable {
+		return
+	}
+	if reachable {
+		log.Log.Infof("container runtime %s is reachable on %s", c.name, c.socketPath)
+	} else {
+		log.Log.Errorf("container runtime %s is unreachable on %s: %v", c.name, c.socketPath, err)
+	}
+	c.reachable = &reachable
+}
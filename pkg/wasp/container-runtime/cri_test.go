@@ -0,0 +1,124 @@
+package container_runtime
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+type fakeRuntimeService struct {
+	runtimeapi.UnimplementedRuntimeServiceServer
+	hang chan struct{}
+}
+
+func (f *fakeRuntimeService) Version(_ context.Context, _ *runtimeapi.VersionRequest) (*runtimeapi.VersionResponse, error) {
+	return &runtimeapi.VersionResponse{RuntimeName: "fake"}, nil
+}
+
+func (f *fakeRuntimeService) ContainerStatus(ctx context.Context, request *runtimeapi.ContainerStatusRequest) (*runtimeapi.ContainerStatusResponse, error) {
+	if f.hang != nil {
+		select {
+		case <-f.hang:
+		case <-ctx.Done():
+		}
+	}
+	if request.ContainerId != "0123abcd" {
+		return nil, status.Error(codes.NotFound, "no such container")
+	}
+	return &runtimeapi.ContainerStatusResponse{
+		Status: &runtimeapi.ContainerStatus{Id: request.ContainerId},
+		Info:   map[string]string{"info": `{"pid": 4242}`},
+	}, nil
+}
+
+var _ = Describe("CRI runtime", func() {
+	var (
+		socketPath string
+		service    *fakeRuntimeService
+		server     *grpc.Server
+		runtime    *criRuntime
+	)
+
+	startServer := func() {
+		Expect(os.RemoveAll(socketPath)).To(Succeed())
+		listener, err := net.Listen("unix", socketPath)
+		Expect(err).ToNot(HaveOccurred())
+		server = grpc.NewServer()
+		runtimeapi.RegisterRuntimeServiceServer(server, service)
+		go func() {
+			defer GinkgoRecover()
+			_ = server.Serve(listener)
+		}()
+	}
+
+	BeforeEach(func() {
+		dir, err := os.MkdirTemp("", "cri")
+		Expect(err).ToNot(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+		socketPath = filepath.Join(dir, "cri.sock")
+		service = &fakeRuntimeService{}
+		startServer()
+		runtime = newCriRuntime("fake", "fake", socketPath, "fake", 200*time.Millisecond)
+		DeferCleanup(func() {
+			server.Stop()
+			Expect(runtime.Close()).To(Succeed())
+		})
+	})
+
+	It("should read the container PID", func() {
+		pid, err := runtime.ContainerPid("0123abcd")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(pid).To(Equal(4242))
+	})
+
+	It("should keep the connection between calls", func() {
+		_, err := runtime.ContainerPid("0123abcd")
+		Expect(err).ToNot(HaveOccurred())
+		conn := runtime.conn
+		_, err = runtime.ContainerPid("0123abcd")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(runtime.conn).To(BeIdenticalTo(conn))
+	})
+
+	It("should consider the runtime reachable when the container does not exist", func() {
+		_, err := runtime.ContainerPid("4567ef01")
+		Expect(status.Code(err)).To(Equal(codes.NotFound))
+		Expect(*runtime.reachable).To(BeTrue())
+	})
+
+	It("should time out when the runtime hangs", func() {
+		service.hang = make(chan struct{})
+		defer close(service.hang)
+		_, err := runtime.ContainerPid("0123abcd")
+		Expect(status.Code(err)).To(Equal(codes.DeadlineExceeded))
+		Expect(*runtime.reachable).To(BeFalse())
+	})
+
+	It("should not connect on health checks before the first call", func() {
+		Expect(runtime.HealthCheck()).To(Succeed())
+		Expect(runtime.conn).To(BeNil())
+	})
+
+	It("should reconnect when the runtime restarts", func() {
+		_, err := runtime.ContainerPid("0123abcd")
+		Expect(err).ToNot(HaveOccurred())
+
+		server.Stop()
+		Eventually(runtime.HealthCheck).Should(HaveOccurred())
+		Expect(*runtime.reachable).To(BeFalse())
+
+		startServer()
+		Eventually(runtime.HealthCheck).WithTimeout(10 * time.Second).Should(Succeed())
+		Expect(*runtime.reachable).To(BeTrue())
+	})
+})
@@ -0,0 +1,13 @@
+package container_runtime
+
+import "time"
+
+const (
+	CrioName       = "cri-o"
+	CrioSocketPath = "/var/run/crio/crio.sock"
+)
+
+// NewCrio returns the CRI-O runtime listening on socketPath
+func NewCrio(socketPath string, callTimeout time.Duration) ContainerRuntime {
+	return newCriRuntime(CrioName, "cri-o", socketPath, "crio", callTimeout)
+}
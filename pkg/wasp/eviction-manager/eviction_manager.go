@@ -0,0 +1,186 @@
+package eviction_manager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift-virtualization/wasp-agent/pkg/log"
+	"github.com/openshift-virtualization/wasp-agent/pkg/monitoring/metrics"
+	limited_swap_manager "github.com/openshift-virtualization/wasp-agent/pkg/wasp/limited-swap-manager"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	kapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	// EvictedReason is the reason of the Event recorded on a pod evicted to relieve swap pressure
+	EvictedReason = "SwapPressureEviction"
+	// EvictionBlockedReason is the reason of the Event recorded on a pod whose eviction was refused,
+	// usually because of a PodDisruptionBudget
+	EvictionBlockedReason = "SwapPressureEvictionBlocked"
+)
+
+// Configuration is the configuration of the EvictionManager
+type Configuration struct {
+	// SwapUtilizationThreshold is the fraction of the node swap in use above which pods are evicted
+	SwapUtilizationThreshold float64
+	// SwapTrafficThreshold is the number of pages swapped in and out per second above which pods are evicted,
+	// 0 disables the signal
+	SwapTrafficThreshold int64
+	// MinPodSwap is the swap in bytes a pod must use to be evicted
+	MinPodSwap int64
+	// Interval is how often the eviction signals are sampled
+	Interval time.Duration
+	// Cooldown is the time after an eviction during which no other pod is evicted,
+	// so that the swap of the evicted pod is freed before the signals are trusted again
+	Cooldown time.Duration
+}
+
+// ContainerUsageSource samples the memory and swap usage of the containers whose swap is managed by the agent
+type ContainerUsageSource interface {
+	ContainerMemoryUsage() ([]limited_swap_manager.ContainerMemoryUsage, error)
+}
+
+// EvictionManager evicts the burstable pods using the most swap when the node runs out of swap or swaps too much.
+// Pods are evicted one at a time through the Eviction API, which honors their PodDisruptionBudgets.
+type EvictionManager struct {
+	pods         corev1client.PodsGetter
+	podLister    v1lister.PodLister
+	usageSource  ContainerUsageSource
+	recorder     record.EventRecorder
+	config       Configuration
+	dryRun       bool
+	sampleSwap   func(now time.Time) (swapSample, error)
+	now          func() time.Time
+	lastSample   *swapSample
+	lastEviction time.Time
+}
+
+// New creates an EvictionManager. In dry-run mode the pods that would be evicted are only logged.
+func New(pods corev1client.PodsGetter,
+	podInformer cache.SharedIndexInformer,
+	usageSource ContainerUsageSource,
+	recorder record.EventRecorder,
+	config Configuration,
+	dryRun bool,
+) *EvictionManager {
+	return &EvictionManager{
+		pods:        pods,
+		podLister:   v1lister.NewPodLister(podInformer.GetIndexer()),
+		usageSource: usageSource,
+		recorder:    recorder,
+		config:      config,
+		dryRun:      dryRun,
+		sampleSwap:  sampleSwap,
+		now:         time.Now,
+	}
+}
+
+// Run samples the eviction signals until stop is closed, the pod informer must be synced
+func (em *EvictionManager) Run(stop <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	log.Log.Infof("Starting EvictionManager")
+	defer log.Log.Infof("Shutting down EvictionManager")
+
+	wait.Until(em.evaluate, em.config.Interval, stop)
+}
+
+// evaluate samples the eviction signals and evicts at most one pod when one of them is above its threshold
+func (em *EvictionManager) evaluate() {
+	now := em.now()
+	sample, err := em.sampleSwap(now)
+	if err != nil {
+		log.Log.Errorf("EvictionManager: couldn't sample the node swap: %v", err)
+		return
+	}
+	s := newSignals(em.lastSample, sample)
+	em.lastSample = &sample
+	if sample.total == 0 {
+		return
+	}
+	metrics.SetEvictionSignal(metrics.EvictionSignalUtilization, s.utilizationExceeded(em.config))
+	metrics.SetEvictionSignal(metrics.EvictionSignalTraffic, s.trafficExceeded(em.config))
+
+	pressure := s.pressure(em.config)
+	if pressure == "" {
+		return
+	}
+	if !em.lastEviction.IsZero() && now.Sub(em.lastEviction) < em.config.Cooldown {
+		log.Log.V(2).Infof("EvictionManager: %s, waiting for the cooldown of the last eviction", pressure)
+		return
+	}
+
+	usages, err := em.usageSource.ContainerMemoryUsage()
+	if err != nil {
+		log.Log.Errorf("EvictionManager: %s but the swap usage of the pods is unknown: %v", pressure, err)
+		return
+	}
+	candidates := newCandidates(usages, em.getPod, em.config.MinPodSwap)
+	rank(candidates)
+	for _, c := range candidates {
+		if em.evict(c, pressure) {
+			em.lastEviction = now
+			return
+		}
+	}
+	log.Log.Infof("EvictionManager: %s but no pod can be evicted", pressure)
+}
+
+func (em *EvictionManager) getPod(namespace, name string) *v1.Pod {
+	pod, err := em.podLister.Pods(namespace).Get(name)
+	if err != nil {
+		return nil
+	}
+	return pod
+}
+
+// evict requests the eviction of a candidate, it returns false when the next candidate should be tried instead
+func (em *EvictionManager) evict(c candidate, pressure string) bool {
+	message := fmt.Sprintf("%s, the pod uses %s of swap and %s of memory for a memory request of %s",
+		pressure, formatBytes(c.swap), formatBytes(c.memory), formatBytes(c.request))
+	if c.exceedsLimit() {
+		message += fmt.Sprintf(" and a memory limit of %s", formatBytes(c.limit))
+	}
+
+	if em.dryRun {
+		log.Log.Infof("EvictionManager: dry-run, would evict %s/%s: %s", c.pod.Namespace, c.pod.Name, message)
+		metrics.IncEviction(metrics.EvictionDryRun)
+		return true
+	}
+
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Name: c.pod.Name, Namespace: c.pod.Namespace},
+	}
+	err := em.pods.Pods(c.pod.Namespace).EvictV1(context.TODO(), eviction)
+	switch {
+	case err == nil:
+		log.Log.Infof("EvictionManager: evicted %s/%s: %s", c.pod.Namespace, c.pod.Name, message)
+		em.recorder.Event(c.pod, v1.EventTypeWarning, EvictedReason, "Evicted: "+message)
+		metrics.IncEviction(metrics.EvictionEvicted)
+		return true
+	case kapierrors.IsTooManyRequests(err):
+		log.Log.Infof("EvictionManager: eviction of %s/%s refused: %v", c.pod.Namespace, c.pod.Name, err)
+		em.recorder.Eventf(c.pod, v1.EventTypeWarning, EvictionBlockedReason, "Eviction refused, probably by a PodDisruptionBudget: %s: %v", message, err)
+		metrics.IncEviction(metrics.EvictionBlocked)
+		return false
+	case kapierrors.IsNotFound(err):
+		return false
+	default:
+		log.Log.Errorf("EvictionManager: failed to evict %s/%s: %v", c.pod.Namespace, c.pod.Name, err)
+		metrics.IncEviction(metrics.EvictionFailed)
+		return false
+	}
+}
+
+func formatBytes(value int64) string {
+	return resource.NewQuantity(value, resource.BinarySI).String()
+}
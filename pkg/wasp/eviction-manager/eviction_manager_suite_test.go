@@ -0,0 +1,13 @@
+package eviction_manager
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestEvictionManager(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "EvictionManager Suite")
+}
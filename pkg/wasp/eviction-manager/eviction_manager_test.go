@@ -0,0 +1,263 @@
+package eviction_manager
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	limited_swap_manager "github.com/openshift-virtualization/wasp-agent/pkg/wasp/limited-swap-manager"
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	kapierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/kubernetes/pkg/apis/scheduling"
+	"k8s.io/utils/pointer"
+)
+
+const gi = 1024 * 1024 * 1024
+
+// fakePods records the evictions, the pods listed in blocked are refused like a PodDisruptionBudget would
+type fakePods struct {
+	corev1client.PodInterface
+	blocked   map[string]bool
+	evictions []string
+}
+
+func (f *fakePods) Pods(_ string) corev1client.PodInterface {
+	return f
+}
+
+func (f *fakePods) EvictV1(_ context.Context, eviction *policyv1.Eviction) error {
+	if f.blocked[eviction.Name] {
+		return kapierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+	}
+	f.evictions = append(f.evictions, eviction.Namespace+"/"+eviction.Name)
+	return nil
+}
+
+type fakeUsageSource []limited_swap_manager.ContainerMemoryUsage
+
+func (f fakeUsageSource) ContainerMemoryUsage() ([]limited_swap_manager.ContainerMemoryUsage, error) {
+	return f, nil
+}
+
+func newBurstablePod(name string, request, limit string) *v1.Pod {
+	resources := v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse(request)}}
+	if limit != "" {
+		resources.Limits = v1.ResourceList{v1.ResourceMemory: resource.MustParse(limit)}
+	}
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns"},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "c", Resources: resources}}},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+func usageOf(pod string, memory, swap uint64) limited_swap_manager.ContainerMemoryUsage {
+	return limited_swap_manager.ContainerMemoryUsage{Namespace: "ns", Pod: pod, Container: "c", Memory: memory, Swap: swap}
+}
+
+var _ = Describe("Eviction manager", func() {
+	var (
+		pods     *fakePods
+		indexer  cache.Indexer
+		recorder *record.FakeRecorder
+		usages   fakeUsageSource
+		swap     swapSample
+		now      time.Time
+		config   Configuration
+	)
+
+	newEvictionManager := func(dryRun bool) *EvictionManager {
+		return &EvictionManager{
+			pods:        pods,
+			podLister:   v1lister.NewPodLister(indexer),
+			usageSource: &usages,
+			recorder:    recorder,
+			config:      config,
+			dryRun:      dryRun,
+			sampleSwap: func(t time.Time) (swapSample, error) {
+				sample := swap
+				sample.time = t
+				return sample, nil
+			},
+			now: func() time.Time { return now },
+		}
+	}
+
+	addPods := func(podList ...*v1.Pod) {
+		for _, pod := range podList {
+			Expect(indexer.Add(pod)).To(Succeed())
+		}
+	}
+
+	BeforeEach(func() {
+		pods = &fakePods{blocked: map[string]bool{}}
+		indexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+		recorder = record.NewFakeRecorder(10)
+		usages = nil
+		swap = swapSample{total: 10 * gi, used: 5 * gi}
+		now = time.Now()
+		config = Configuration{SwapUtilizationThreshold: 0.9, Interval: time.Second, Cooldown: time.Minute}
+	})
+
+	It("should not evict pods below the thresholds", func() {
+		addPods(newBurstablePod("a", "1Gi", ""))
+		usages = fakeUsageSource{usageOf("a", gi, gi)}
+		newEvictionManager(false).evaluate()
+		Expect(pods.evictions).To(BeEmpty())
+	})
+
+	It("should evict the worst offender when the swap utilization is above the threshold", func() {
+		swap.used = 95 * gi / 10
+		addPods(newBurstablePod("a", "1Gi", ""), newBurstablePod("b", "1Gi", ""))
+		usages = fakeUsageSource{usageOf("a", gi, gi), usageOf("b", gi, 3*gi)}
+		newEvictionManager(false).evaluate()
+		Expect(pods.evictions).To(Equal([]string{"ns/b"}))
+		Expect(recorder.Events).To(Receive(ContainSubstring(EvictedReason)))
+	})
+
+	It("should evict when the swap traffic is above the threshold", func() {
+		config.SwapTrafficThreshold = 100
+		addPods(newBurstablePod("a", "1Gi", ""))
+		usages = fakeUsageSource{usageOf("a", gi, gi)}
+		em := newEvictionManager(false)
+
+		em.evaluate()
+		Expect(pods.evictions).To(BeEmpty())
+
+		now = now.Add(10 * time.Second)
+		swap.swapped += 2000 * swapPageSize * 10
+		em.evaluate()
+		Expect(pods.evictions).To(Equal([]string{"ns/a"}))
+	})
+
+	It("should try the next pod when a PodDisruptionBudget refuses the eviction", func() {
+		swap.used = swap.total
+		pods.blocked["b"] = true
+		addPods(newBurstablePod("a", "1Gi", ""), newBurstablePod("b", "1Gi", ""))
+		usages = fakeUsageSource{usageOf("a", gi, gi), usageOf("b", gi, 3*gi)}
+		newEvictionManager(false).evaluate()
+		Expect(pods.evictions).To(Equal([]string{"ns/a"}))
+		Expect(recorder.Events).To(Receive(ContainSubstring(EvictionBlockedReason)))
+		Expect(recorder.Events).To(Receive(ContainSubstring(EvictedReason)))
+	})
+
+	It("should wait for the cooldown before evicting another pod", func() {
+		swap.used = swap.total
+		addPods(newBurstablePod("a", "1Gi", ""), newBurstablePod("b", "1Gi", ""))
+		usages = fakeUsageSource{usageOf("a", gi, gi), usageOf("b", gi, 3*gi)}
+		em := newEvictionManager(false)
+
+		em.evaluate()
+		now = now.Add(config.Cooldown / 2)
+		em.evaluate()
+		Expect(pods.evictions).To(Equal([]string{"ns/b"}))
+
+		now = now.Add(config.Cooldown)
+		em.evaluate()
+		Expect(pods.evictions).To(HaveLen(2))
+	})
+
+	It("should only log the eviction in dry-run mode", func() {
+		swap.used = swap.total
+		addPods(newBurstablePod("a", "1Gi", ""))
+		usages = fakeUsageSource{usageOf("a", gi, gi)}
+		newEvictionManager(true).evaluate()
+		Expect(pods.evictions).To(BeEmpty())
+		Expect(recorder.Events).To(BeEmpty())
+	})
+
+	It("should not evict pods using less swap than the minimum", func() {
+		swap.used = swap.total
+		config.MinPodSwap = gi
+		addPods(newBurstablePod("a", "1Gi", ""))
+		usages = fakeUsageSource{usageOf("a", gi, gi/2)}
+		newEvictionManager(false).evaluate()
+		Expect(pods.evictions).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Eviction ranking", func() {
+	getPod := func(podList ...*v1.Pod) func(string, string) *v1.Pod {
+		return func(_, name string) *v1.Pod {
+			for _, pod := range podList {
+				if pod.Name == name {
+					return pod
+				}
+			}
+			return nil
+		}
+	}
+
+	names := func(candidates []candidate) []string {
+		var result []string
+		for _, c := range candidates {
+			result = append(result, c.pod.Name)
+		}
+		return result
+	}
+
+	It("should skip the pods that must not be evicted", func() {
+		critical := newBurstablePod("critical", "1Gi", "")
+		critical.Spec.Priority = pointer.Int32(scheduling.SystemCriticalPriority)
+		mirror := newBurstablePod("mirror", "1Gi", "")
+		mirror.Annotations = map[string]string{v1.MirrorPodAnnotationKey: "mirror"}
+		terminating := newBurstablePod("terminating", "1Gi", "")
+		terminating.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+		guaranteed := newBurstablePod("guaranteed", "1Gi", "1Gi")
+		guaranteed.Spec.Containers[0].Resources.Requests[v1.ResourceCPU] = resource.MustParse("1")
+		guaranteed.Spec.Containers[0].Resources.Limits[v1.ResourceCPU] = resource.MustParse("1")
+
+		candidates := newCandidates([]limited_swap_manager.ContainerMemoryUsage{
+			usageOf("critical", gi, gi),
+			usageOf("mirror", gi, gi),
+			usageOf("terminating", gi, gi),
+			usageOf("guaranteed", gi, gi),
+			usageOf("gone", gi, gi),
+			usageOf("burstable", gi, gi),
+		}, getPod(critical, mirror, terminating, guaranteed, newBurstablePod("burstable", "1Gi", "")), 0)
+		Expect(names(candidates)).To(Equal([]string{"burstable"}))
+	})
+
+	It("should sum the usage of the containers of a pod", func() {
+		pod := newBurstablePod("a", "1Gi", "")
+		candidates := newCandidates([]limited_swap_manager.ContainerMemoryUsage{
+			usageOf("a", gi, gi),
+			usageOf("a", gi, gi),
+		}, getPod(pod), 0)
+		Expect(candidates).To(HaveLen(1))
+		Expect(candidates[0].memory).To(Equal(int64(2 * gi)))
+		Expect(candidates[0].swap).To(Equal(int64(2 * gi)))
+	})
+
+	It("should rank by limit, request, priority and usage above the request", func() {
+		lowPriority := func(c candidate) candidate {
+			c.pod = c.pod.DeepCopy()
+			c.pod.Spec.Priority = pointer.Int32(-10)
+			return c
+		}
+		candidates := []candidate{
+			{pod: newBurstablePod("below-request", "4Gi", ""), memory: gi, swap: gi, request: 4 * gi},
+			lowPriority(candidate{pod: newBurstablePod("below-request-low-priority", "4Gi", ""), memory: gi, swap: gi, request: 4 * gi}),
+			{pod: newBurstablePod("above-request", "1Gi", ""), memory: gi, swap: gi, request: gi},
+			{pod: newBurstablePod("far-above-request", "1Gi", ""), memory: gi, swap: 3 * gi, request: gi},
+			{pod: newBurstablePod("above-limit", "1Gi", "2Gi"), memory: 2 * gi, swap: gi / 2, request: gi, limit: 2 * gi},
+		}
+		rank(candidates)
+		Expect(names(candidates)).To(Equal([]string{
+			"above-limit",
+			"far-above-request",
+			"above-request",
+			"below-request-low-priority",
+			"below-request",
+		}))
+	})
+})
@@ -0,0 +1,124 @@
+package eviction_manager
+
+import (
+	"sort"
+
+	limited_swap_manager "github.com/openshift-virtualization/wasp-agent/pkg/wasp/limited-swap-manager"
+	v1 "k8s.io/api/core/v1"
+	kubeapiqos "k8s.io/kubernetes/pkg/apis/core/v1/helper/qos"
+	kubelettypes "k8s.io/kubernetes/pkg/kubelet/types"
+)
+
+// candidate is a pod that can be evicted, with the memory and swap used by its managed containers
+type candidate struct {
+	pod    *v1.Pod
+	memory int64
+	swap   int64
+	// request is the memory request of the pod
+	request int64
+	// limit is the memory limit of the pod, 0 when one of its containers has no limit
+	limit int64
+}
+
+// usage is the memory and swap used by the pod, the memory usage of a pod that swaps can exceed its limit
+func (c candidate) usage() int64 {
+	return c.memory + c.swap
+}
+
+func (c candidate) exceedsLimit() bool {
+	return c.limit > 0 && c.usage() > c.limit
+}
+
+func (c candidate) exceedsRequest() bool {
+	return c.usage() > c.request
+}
+
+func (c candidate) priority() int32 {
+	if c.pod.Spec.Priority == nil {
+		return 0
+	}
+	return *c.pod.Spec.Priority
+}
+
+// evictable returns whether the pod may be evicted to relieve swap pressure.
+// Static, mirror and critical pods are never evicted, nor are pods that are not burstable since they get no swap.
+func evictable(pod *v1.Pod) bool {
+	return pod.Status.Phase == v1.PodRunning &&
+		pod.DeletionTimestamp == nil &&
+		kubeapiqos.GetPodQOS(pod) == v1.PodQOSBurstable &&
+		!kubelettypes.IsStaticPod(pod) &&
+		!kubelettypes.IsMirrorPod(pod) &&
+		!kubelettypes.IsCriticalPod(pod)
+}
+
+// newCandidates groups the container usages by pod, getPod returns nil for the pods that are gone
+func newCandidates(usages []limited_swap_manager.ContainerMemoryUsage, getPod func(namespace, name string) *v1.Pod, minPodSwap int64) []candidate {
+	byPod := map[string]*candidate{}
+	var keys []string
+	for _, usage := range usages {
+		key := usage.Namespace + "/" + usage.Pod
+		c, ok := byPod[key]
+		if !ok {
+			pod := getPod(usage.Namespace, usage.Pod)
+			if pod == nil || !evictable(pod) {
+				byPod[key] = nil
+				continue
+			}
+			c = &candidate{pod: pod}
+			c.request, c.limit = podMemory(pod)
+			byPod[key] = c
+			keys = append(keys, key)
+		} else if c == nil {
+			continue
+		}
+		c.memory += int64(usage.Memory)
+		c.swap += int64(usage.Swap)
+	}
+
+	var candidates []candidate
+	for _, key := range keys {
+		// evicting a pod that barely swaps does not relieve the swap pressure
+		if c := byPod[key]; c.swap > minPodSwap {
+			candidates = append(candidates, *c)
+		}
+	}
+	return candidates
+}
+
+// podMemory returns the memory request and limit of the pod, the limit is 0 when one of its containers has no limit
+func podMemory(pod *v1.Pod) (request int64, limit int64) {
+	unlimited := false
+	for _, container := range pod.Spec.Containers {
+		request += container.Resources.Requests.Memory().Value()
+		if container.Resources.Limits.Memory().IsZero() {
+			unlimited = true
+		}
+		limit += container.Resources.Limits.Memory().Value()
+	}
+	if unlimited {
+		return request, 0
+	}
+	return request, limit
+}
+
+// rank orders the candidates like the kubelet orders the pods to evict under memory pressure,
+// with the pods whose usage exceeds their limit first:
+// pods exceeding their limit, pods exceeding their request, lower priority, then higher usage above the request
+func rank(candidates []candidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.exceedsLimit() != b.exceedsLimit() {
+			return a.exceedsLimit()
+		}
+		if a.exceedsRequest() != b.exceedsRequest() {
+			return a.exceedsRequest()
+		}
+		if a.priority() != b.priority() {
+			return a.priority() < b.priority()
+		}
+		if a.usage()-a.request != b.usage()-b.request {
+			return a.usage()-a.request > b.usage()-b.request
+		}
+		return a.pod.Namespace+"/"+a.pod.Name < b.pod.Namespace+"/"+b.pod.Name
+	})
+}
@@ -0,0 +1,70 @@
+package eviction_manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shirou/gopsutil/mem"
+)
+
+// swapPageSize is the size of the pages gopsutil counts the pswpin and pswpout values of /proc/vmstat in
+const swapPageSize = 4 * 1024
+
+// swapSample is the swap of the node at a point in time
+type swapSample struct {
+	time  time.Time
+	total uint64
+	used  uint64
+	// swapped is the number of bytes swapped in and out since boot
+	swapped uint64
+}
+
+func sampleSwap(now time.Time) (swapSample, error) {
+	swap, err := mem.SwapMemory()
+	if err != nil {
+		return swapSample{}, err
+	}
+	return swapSample{time: now, total: swap.Total, used: swap.Used, swapped: swap.Sin + swap.Sout}, nil
+}
+
+// signals are the swap eviction signals of the node
+type signals struct {
+	// utilization is the fraction of the node swap in use
+	utilization float64
+	// traffic is the number of pages swapped in and out per second since the previous sample,
+	// nil on the first sample
+	traffic *float64
+}
+
+func newSignals(previous *swapSample, current swapSample) signals {
+	s := signals{}
+	if current.total > 0 {
+		s.utilization = float64(current.used) / float64(current.total)
+	}
+	if previous != nil && current.time.After(previous.time) && current.swapped >= previous.swapped {
+		traffic := float64(current.swapped-previous.swapped) / swapPageSize / current.time.Sub(previous.time).Seconds()
+		s.traffic = &traffic
+	}
+	return s
+}
+
+// utilizationExceeded returns whether the swap utilization of the node is above the threshold
+func (s signals) utilizationExceeded(config Configuration) bool {
+	return s.utilization > config.SwapUtilizationThreshold
+}
+
+// trafficExceeded returns whether the swap traffic of the node is above the threshold, a zero threshold disables the signal
+func (s signals) trafficExceeded(config Configuration) bool {
+	return config.SwapTrafficThreshold > 0 && s.traffic != nil && *s.traffic > float64(config.SwapTrafficThreshold)
+}
+
+// pressure describes the signals above their threshold, it is empty when the node is not under swap pressure
+func (s signals) pressure(config Configuration) string {
+	if s.utilizationExceeded(config) {
+		return fmt.Sprintf("node swap utilization %.0f%% exceeds the threshold of %.0f%%", s.utilization*100, config.SwapUtilizationThreshold*100)
+	}
+	if s.trafficExceeded(config) {
+		return fmt.Sprintf("node swap traffic of %.0f pages/s exceeds the threshold of %d pages/s", *s.traffic, config.SwapTrafficThreshold)
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package limited_swap_manager
+
+import (
+	"fmt"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// SwapDisabledAnnotation opts a pod out of swap when set to "true"
+	SwapDisabledAnnotation = "wasp.io/swap-disabled"
+	// SwapLimitAnnotation caps the swap of each container of the pod, e.g. "512Mi"
+	SwapLimitAnnotation = "wasp.io/swap-limit"
+	// SwapRatioAnnotation scales the swap share computed by the swap policy, e.g. "0.5"
+	SwapRatioAnnotation = "wasp.io/swap-ratio"
+	// PodSwapLimitAnnotation caps the swap of the pod cgroup, e.g. "1Gi"
+	PodSwapLimitAnnotation = "wasp.io/pod-swap-limit"
+
+	InvalidSwapAnnotationReason = "InvalidSwapAnnotation"
+)
+
+// swapOverrides holds the validated swap annotations of a pod.
+// Annotations can only reduce or scale the swap of eligible containers, never grant swap
+// to containers that are not eligible for it.
+type swapOverrides struct {
+	disabled bool
+	limit    *int64
+	ratio    *float64
+	podLimit *int64
+}
+
+// parseSwapOverrides returns the valid overrides of the pod and an error for each rejected annotation
+func parseSwapOverrides(pod *v1.Pod) (swapOverrides, []error) {
+	overrides := swapOverrides{}
+	var errs []error
+
+	if value, ok := pod.Annotations[SwapDisabledAnnotation]; ok {
+		disabled, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s annotation %q: must be a boolean", SwapDisabledAnnotation, value))
+		} else {
+			overrides.disabled = disabled
+		}
+	}
+
+	if value, ok := pod.Annotations[SwapLimitAnnotation]; ok {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil || quantity.Sign() < 0 {
+			errs = append(errs, fmt.Errorf("invalid %s annotation %q: must be a non-negative quantity", SwapLimitAnnotation, value))
+		} else {
+			limit := quantity.Value()
+			overrides.limit = &limit
+		}
+	}
+
+	if value, ok := pod.Annotations[SwapRatioAnnotation]; ok {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil || ratio < 0 {
+			errs = append(errs, fmt.Errorf("invalid %s annotation %q: must be a non-negative number", SwapRatioAnnotation, value))
+		} else {
+			overrides.ratio = &ratio
+		}
+	}
+
+	if value, ok := pod.Annotations[PodSwapLimitAnnotation]; ok {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil || quantity.Sign() < 0 {
+			errs = append(errs, fmt.Errorf("invalid %s annotation %q: must be a non-negative quantity", PodSwapLimitAnnotation, value))
+		} else {
+			podLimit := quantity.Value()
+			overrides.podLimit = &podLimit
+		}
+	}
+
+	return overrides, errs
+}
+
+// apply adjusts the swap limit computed by the swap policy
+func (o swapOverrides) apply(swapLimit, swapCapacity int64) int64 {
+	if o.disabled {
+		return 0
+	}
+	if o.ratio != nil && swapLimit != UnlimitedSwap {
+		swapLimit = int64(float64(swapLimit) * *o.ratio)
+		if swapLimit > swapCapacity {
+			swapLimit = swapCapacity
+		}
+	}
+	if o.limit != nil && (swapLimit == UnlimitedSwap || swapLimit > *o.limit) {
+		swapLimit = *o.limit
+	}
+	return swapLimit
+}
+
+// applyToPod adjusts the swap limit of the pod cgroup computed from its containers
+func (o swapOverrides) applyToPod(podSwapLimit int64) int64 {
+	if o.disabled {
+		return 0
+	}
+	if o.podLimit != nil && (podSwapLimit == UnlimitedSwap || podSwapLimit > *o.podLimit) {
+		podSwapLimit = *o.podLimit
+	}
+	return podSwapLimit
+}
@@ -0,0 +1,89 @@
+package limited_swap_manager
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func podWithAnnotations(annotations map[string]string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test",
+			Namespace:   "default",
+			Annotations: annotations,
+		},
+	}
+}
+
+var _ = Describe("Swap annotations", func() {
+	const swapCapacity = int64(8 * 1024 * 1024 * 1024)
+
+	DescribeTable("should adjust the swap limit", func(annotations map[string]string, swapLimit, expected int64) {
+		overrides, errs := parseSwapOverrides(podWithAnnotations(annotations))
+		Expect(errs).To(BeEmpty())
+		Expect(overrides.apply(swapLimit, swapCapacity)).To(Equal(expected))
+	},
+		Entry("without annotations", nil, int64(1000), int64(1000)),
+		Entry("when swap is disabled", map[string]string{SwapDisabledAnnotation: "true"}, int64(1000), int64(0)),
+		Entry("when swap is explicitly enabled", map[string]string{SwapDisabledAnnotation: "false"}, int64(1000), int64(1000)),
+		Entry("when the limit is lower", map[string]string{SwapLimitAnnotation: "500"}, int64(1000), int64(500)),
+		Entry("when the limit is higher", map[string]string{SwapLimitAnnotation: "2000"}, int64(1000), int64(1000)),
+		Entry("when the policy grants unlimited swap", map[string]string{SwapLimitAnnotation: "1Gi"}, UnlimitedSwap, int64(1024*1024*1024)),
+		Entry("when a ratio is set", map[string]string{SwapRatioAnnotation: "0.5"}, int64(1000), int64(500)),
+		Entry("when the ratio exceeds node swap", map[string]string{SwapRatioAnnotation: "100"}, swapCapacity/2, swapCapacity),
+		Entry("when both ratio and limit are set", map[string]string{SwapRatioAnnotation: "2", SwapLimitAnnotation: "1500"}, int64(1000), int64(1500)),
+		Entry("when disabled overrides everything", map[string]string{SwapDisabledAnnotation: "true", SwapRatioAnnotation: "2"}, int64(1000), int64(0)),
+	)
+
+	DescribeTable("should reject invalid annotations", func(annotations map[string]string) {
+		overrides, errs := parseSwapOverrides(podWithAnnotations(annotations))
+		Expect(errs).To(HaveLen(1))
+		Expect(overrides.apply(1000, swapCapacity)).To(Equal(int64(1000)))
+	},
+		Entry("non boolean swap-disabled", map[string]string{SwapDisabledAnnotation: "yes please"}),
+		Entry("malformed swap-limit", map[string]string{SwapLimitAnnotation: "a lot"}),
+		Entry("negative swap-limit", map[string]string{SwapLimitAnnotation: "-1Gi"}),
+		Entry("malformed swap-ratio", map[string]string{SwapRatioAnnotation: "half"}),
+		Entry("negative swap-ratio", map[string]string{SwapRatioAnnotation: "-0.5"}),
+		Entry("malformed pod-swap-limit", map[string]string{PodSwapLimitAnnotation: "all of it"}),
+		Entry("negative pod-swap-limit", map[string]string{PodSwapLimitAnnotation: "-1Gi"}),
+	)
+
+	DescribeTable("should adjust the pod swap limit", func(annotations map[string]string, podSwapLimit, expected int64) {
+		overrides, errs := parseSwapOverrides(podWithAnnotations(annotations))
+		Expect(errs).To(BeEmpty())
+		Expect(overrides.applyToPod(podSwapLimit)).To(Equal(expected))
+	},
+		Entry("without annotations", nil, int64(1000), int64(1000)),
+		Entry("when the limit is lower", map[string]string{PodSwapLimitAnnotation: "500"}, int64(1000), int64(500)),
+		Entry("when the limit is zero", map[string]string{PodSwapLimitAnnotation: "0"}, int64(1000), int64(0)),
+		Entry("when the containers get unlimited swap", map[string]string{PodSwapLimitAnnotation: "1Gi"}, UnlimitedSwap, int64(1024*1024*1024)),
+	)
+
+	It("should report invalid annotations only when they change", func() {
+		recorder := record.NewFakeRecorder(10)
+		lsm := &LimitedSwapManager{recorder: recorder}
+		pod := podWithAnnotations(map[string]string{SwapLimitAnnotation: "a lot"})
+
+		for i := 0; i < 3; i++ {
+			_, errs := parseSwapOverrides(pod)
+			lsm.recordAnnotationErrors("default/test", pod, errs)
+		}
+		Expect(recorder.Events).To(HaveLen(1))
+		Expect(recorder.Events).To(Receive(ContainSubstring(InvalidSwapAnnotationReason)))
+
+		pod.Annotations[SwapLimitAnnotation] = "too much"
+		_, errs := parseSwapOverrides(pod)
+		lsm.recordAnnotationErrors("default/test", pod, errs)
+		Expect(recorder.Events).To(Receive(ContainSubstring("too much")))
+
+		delete(pod.Annotations, SwapLimitAnnotation)
+		_, errs = parseSwapOverrides(pod)
+		lsm.recordAnnotationErrors("default/test", pod, errs)
+		Expect(recorder.Events).To(BeEmpty())
+	})
+})
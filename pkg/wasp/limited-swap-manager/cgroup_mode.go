@@ -0,0 +1,172 @@
+package limited_swap_manager
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/openshift-virtualization/wasp-agent/pkg/log"
+	"golang.org/x/sys/unix"
+)
+
+type cgroupMode string
+
+const (
+	cgroupV1 cgroupMode = "v1"
+	cgroupV2 cgroupMode = "v2"
+
+	// memoryController is the cgroup v1 hierarchy holding the memory and swap limits
+	memoryController = "memory"
+	memswLimitFile   = "memory.memsw.limit_in_bytes"
+	swapMaxFile      = "memory.swap.max"
+
+	// cgroupV1UnlimitedThreshold is above any real cgroup v1 limit, the kernel reports
+	// unlimited as the largest multiple of the page size that fits in an int64
+	cgroupV1UnlimitedThreshold = math.MaxInt64 / 2
+)
+
+// detectCgroupMode returns the cgroup version mounted on cgroupRoot.
+// Hybrid hierarchies are handled as cgroup v1 since the memory controller is bound to v1,
+// in which case swap accounting must be enabled for swap to be limited.
+func detectCgroupMode(cgroupRoot string) (cgroupMode, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(cgroupRoot, &st); err != nil {
+		return "", fmt.Errorf("failed to detect the cgroup mode of %s: %w", cgroupRoot, err)
+	}
+	if st.Type == unix.CGROUP2_SUPER_MAGIC {
+		return cgroupV2, nil
+	}
+
+	memswLimitPath := filepath.Join(cgroupRoot, memoryController, memswLimitFile)
+	if _, err := os.Stat(memswLimitPath); err != nil {
+		return "", fmt.Errorf("cgroup v1 swap accounting is not available, %s: %w. "+
+			"Boot the node with the swapaccount=1 kernel argument or switch it to cgroup v2", memswLimitPath, err)
+	}
+	return cgroupV1, nil
+}
+
+// cgroupControllerRoot returns the directory the container cgroups are found in
+func cgroupControllerRoot(cgroupRoot string, mode cgroupMode) string {
+	if mode == cgroupV1 {
+		return filepath.Join(cgroupRoot, memoryController)
+	}
+	return cgroupRoot
+}
+
+// swapLimitFile returns the file of the cgroup holding the swap limit
+func swapLimitFile(mode cgroupMode) string {
+	if mode == cgroupV1 {
+		return memswLimitFile
+	}
+	return swapMaxFile
+}
+
+// setSwapLimitForMode sets the swap limit of the cgroup unless the cgroup already has it.
+// It returns the value of the swap limit file found in the cgroup and the value it was set to.
+func setSwapLimitForMode(mode cgroupMode, dirPath string, swapLimit int64) (current int64, applied int64, err error) {
+	current, applied, err = readSwapLimitForMode(mode, dirPath, swapLimit)
+	if err != nil {
+		return 0, 0, err
+	}
+	if current == applied {
+		return current, applied, nil
+	}
+	return current, applied, writeSwapLimitValue(mode, dirPath, applied)
+}
+
+// readSwapLimitForMode returns the value of the swap limit file found in the cgroup and the value it should have for swapLimit
+func readSwapLimitForMode(mode cgroupMode, dirPath string, swapLimit int64) (current int64, desired int64, err error) {
+	desired, err = swapLimitValue(mode, dirPath, swapLimit)
+	if err != nil {
+		return 0, 0, err
+	}
+	current, err = readSwapLimitValue(mode, dirPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	return current, desired, nil
+}
+
+// swapLimitValue translates the swap limit into the value of the swap limit file, UnlimitedSwap when not capped.
+// On cgroup v1 the memory+swap limit is set on top of the memory limit set by the kubelet,
+// containers without a memory limit or with unlimited swap get an unlimited memory+swap limit.
+// Unlike on cgroup v2, the swap of containers without a memory limit is then not capped, since the
+// memory+swap limit would cap their memory as well.
+func swapLimitValue(mode cgroupMode, dirPath string, swapLimit int64) (int64, error) {
+	if mode != cgroupV1 || swapLimit == UnlimitedSwap {
+		return swapLimit, nil
+	}
+
+	memoryLimit, err := readCgroupInt(dirPath, "memory.limit_in_bytes")
+	if err != nil {
+		return 0, err
+	}
+	if memoryLimit > math.MaxInt64-swapLimit || memoryLimit+swapLimit >= cgroupV1UnlimitedThreshold {
+		log.Log.V(2).Infof("LimitedSwapManager: the swap of %s is not capped to %d bytes, it has no memory limit and the node runs cgroup v1", dirPath, swapLimit)
+		return UnlimitedSwap, nil
+	}
+	return memoryLimit + swapLimit, nil
+}
+
+// swapLimitFromValue is the inverse of swapLimitValue, it translates the value of the swap limit file into a swap limit
+func swapLimitFromValue(mode cgroupMode, dirPath string, value int64) (int64, error) {
+	if mode != cgroupV1 || value == UnlimitedSwap {
+		return value, nil
+	}
+
+	memoryLimit, err := readCgroupInt(dirPath, "memory.limit_in_bytes")
+	if err != nil {
+		return 0, err
+	}
+	if value < memoryLimit {
+		return 0, nil
+	}
+	return value - memoryLimit, nil
+}
+
+// formatSwapLimitValue formats the value of the swap limit file for logging
+func formatSwapLimitValue(value int64) string {
+	if value == UnlimitedSwap {
+		return "max"
+	}
+	return strconv.FormatInt(value, 10)
+}
+
+func readSwapLimitValue(mode cgroupMode, dirPath string) (int64, error) {
+	content, err := cgroups.ReadFile(dirPath, swapLimitFile(mode))
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(content)
+	if value == "max" {
+		return UnlimitedSwap, nil
+	}
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if mode == cgroupV1 && limit >= cgroupV1UnlimitedThreshold {
+		return UnlimitedSwap, nil
+	}
+	return limit, nil
+}
+
+func writeSwapLimitValue(mode cgroupMode, dirPath string, limit int64) error {
+	value := strconv.FormatInt(limit, 10)
+	if limit == UnlimitedSwap && mode == cgroupV2 {
+		value = "max"
+	}
+	return cgroups.WriteFile(dirPath, swapLimitFile(mode), value)
+}
+
+func readCgroupInt(dirPath, file string) (int64, error) {
+	content, err := cgroups.ReadFile(dirPath, file)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(content), 10, 64)
+}
@@ -0,0 +1,127 @@
+package limited_swap_manager
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("cgroup v2", func() {
+	var cgroupRoot string
+
+	BeforeEach(func() {
+		cgroupRoot = GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(cgroupRoot, swapMaxFile), []byte("max\n"), 0644)).To(Succeed())
+	})
+
+	readSwapMax := func() string {
+		swapMax, err := os.ReadFile(filepath.Join(cgroupRoot, swapMaxFile))
+		Expect(err).ToNot(HaveOccurred())
+		return string(swapMax)
+	}
+
+	It("should set the swap limit and report the previous one", func() {
+		current, applied, err := setSwapLimitForMode(cgroupV2, cgroupRoot, 1024)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(current).To(Equal(UnlimitedSwap))
+		Expect(applied).To(Equal(int64(1024)))
+		Expect(readSwapMax()).To(Equal("1024"))
+	})
+
+	It("should not write the swap limit when it is already set", func() {
+		Expect(os.WriteFile(filepath.Join(cgroupRoot, swapMaxFile), []byte("1024\n"), 0644)).To(Succeed())
+		current, applied, err := setSwapLimitForMode(cgroupV2, cgroupRoot, 1024)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(current).To(Equal(applied))
+		// the kernel formatting of the file is left untouched
+		Expect(readSwapMax()).To(Equal("1024\n"))
+	})
+
+	It("should write max for unlimited swap", func() {
+		Expect(os.WriteFile(filepath.Join(cgroupRoot, swapMaxFile), []byte("0\n"), 0644)).To(Succeed())
+		_, _, err := setSwapLimitForMode(cgroupV2, cgroupRoot, UnlimitedSwap)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(readSwapMax()).To(Equal("max"))
+	})
+
+	It("should not write the swap limit in dry-run mode", func() {
+		lsm := &LimitedSwapManager{cgroupMode: cgroupV2, dryRun: true}
+		current, applied, err := lsm.applySwapLimit("container test", cgroupRoot, 1024)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(current).To(Equal(UnlimitedSwap))
+		Expect(applied).To(Equal(int64(1024)))
+		Expect(readSwapMax()).To(Equal("max\n"))
+	})
+})
+
+var _ = Describe("cgroup v1", func() {
+	var cgroupRoot string
+
+	BeforeEach(func() {
+		cgroupRoot = GinkgoT().TempDir()
+	})
+
+	It("should refuse to run without swap accounting", func() {
+		Expect(os.MkdirAll(filepath.Join(cgroupRoot, memoryController), 0755)).To(Succeed())
+		_, err := detectCgroupMode(cgroupRoot)
+		Expect(err).To(MatchError(ContainSubstring("swapaccount=1")))
+	})
+
+	It("should detect cgroup v1 with swap accounting", func() {
+		Expect(os.MkdirAll(filepath.Join(cgroupRoot, memoryController), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(cgroupRoot, memoryController, memswLimitFile), []byte("9223372036854771712\n"), 0644)).To(Succeed())
+		mode, err := detectCgroupMode(cgroupRoot)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mode).To(Equal(cgroupV1))
+		Expect(cgroupControllerRoot(cgroupRoot, mode)).To(Equal(filepath.Join(cgroupRoot, memoryController)))
+	})
+
+	DescribeTable("should add the swap limit to the memory limit", func(memoryLimit string, swapLimit int64, expected string) {
+		Expect(os.WriteFile(filepath.Join(cgroupRoot, "memory.limit_in_bytes"), []byte(memoryLimit+"\n"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(cgroupRoot, memswLimitFile), []byte("1\n"), 0644)).To(Succeed())
+		_, _, err := setSwapLimitForMode(cgroupV1, cgroupRoot, swapLimit)
+		Expect(err).ToNot(HaveOccurred())
+		memswLimit, err := os.ReadFile(filepath.Join(cgroupRoot, memswLimitFile))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(memswLimit)).To(Equal(expected))
+	},
+		Entry("no swap", "1073741824", int64(0), "1073741824"),
+		Entry("limited swap", "1073741824", int64(536870912), "1610612736"),
+		Entry("unlimited swap", "1073741824", UnlimitedSwap, "-1"),
+		Entry("no memory limit", "9223372036854771712", int64(536870912), "-1"),
+	)
+
+	It("should read back an unlimited memory+swap limit", func() {
+		Expect(os.WriteFile(filepath.Join(cgroupRoot, "memory.limit_in_bytes"), []byte("9223372036854771712\n"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(cgroupRoot, memswLimitFile), []byte("9223372036854771712\n"), 0644)).To(Succeed())
+		current, applied, err := setSwapLimitForMode(cgroupV1, cgroupRoot, 1024)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(current).To(Equal(UnlimitedSwap))
+		Expect(applied).To(Equal(UnlimitedSwap))
+	})
+
+	It("should translate the memory+swap limit back into a swap limit", func() {
+		Expect(os.WriteFile(filepath.Join(cgroupRoot, "memory.limit_in_bytes"), []byte("1073741824\n"), 0644)).To(Succeed())
+		Expect(swapLimitFromValue(cgroupV1, cgroupRoot, 1610612736)).To(Equal(int64(536870912)))
+		Expect(swapLimitFromValue(cgroupV1, cgroupRoot, 1073741824)).To(BeZero())
+		Expect(swapLimitFromValue(cgroupV1, cgroupRoot, UnlimitedSwap)).To(Equal(UnlimitedSwap))
+	})
+
+	It("should fall back to the memory controller cgroup of the container process", func() {
+		resolver := &cgroupPathResolver{
+			cgroupRoot: filepath.Join(cgroupRoot, memoryController),
+			procRoot:   GinkgoT().TempDir(),
+			controller: memoryController,
+		}
+		procDir := filepath.Join(resolver.procRoot, "4242")
+		Expect(os.MkdirAll(procDir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(procDir, "cgroup"),
+			[]byte("11:cpu,cpuacct:/kubepods/burstable/pod1/0123abcd\n5:memory:/kubepods/burstable/pod1/0123abcd\n"), 0644)).To(Succeed())
+
+		dirPath, err := resolver.processCgroupPath("4242")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dirPath).To(Equal(filepath.Join(cgroupRoot, memoryController, "kubepods/burstable/pod1/0123abcd")))
+	})
+})
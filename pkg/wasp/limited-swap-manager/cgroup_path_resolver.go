@@ -0,0 +1,137 @@
+package limited_swap_manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/openshift-virtualization/wasp-agent/pkg/log"
+	container_runtime "github.com/openshift-virtualization/wasp-agent/pkg/wasp/container-runtime"
+	v1 "k8s.io/api/core/v1"
+	kubeapiqos "k8s.io/kubernetes/pkg/apis/core/v1/helper/qos"
+)
+
+const (
+	procPathBase = "/host/proc"
+
+	kubepodsCgroupName  = "kubepods"
+	podCgroupNamePrefix = "pod"
+	systemdSliceSuffix  = ".slice"
+)
+
+// cgroupPathResolver finds the cgroup directory of a container.
+// The directory is derived from the pod UID, the pod QoS class and the container ID
+// following the kubelet naming conventions of both the systemd and the cgroupfs drivers,
+// the cgroup of the container init process is used when no such directory exists.
+type cgroupPathResolver struct {
+	cgroupRoot string
+	procRoot   string
+	// controller is the cgroup v1 controller of the container cgroup in /proc/<pid>/cgroup, empty on cgroup v2
+	controller string
+}
+
+func newCgroupPathResolver(cgroupRoot string, mode cgroupMode) *cgroupPathResolver {
+	resolver := &cgroupPathResolver{
+		cgroupRoot: cgroupControllerRoot(cgroupRoot, mode),
+		procRoot:   procPathBase,
+	}
+	if mode == cgroupV1 {
+		resolver.controller = memoryController
+	}
+	return resolver
+}
+
+func (r *cgroupPathResolver) containerCgroupPath(pod *v1.Pod, runtime container_runtime.ContainerRuntime, containerID string) (string, error) {
+	for _, podCgroupPath := range podCgroupPaths(pod) {
+		for _, containerCgroupName := range runtime.ContainerCgroupNames(containerID) {
+			dirPath := filepath.Join(r.cgroupRoot, podCgroupPath, containerCgroupName)
+			if _, err := os.Stat(dirPath); err == nil {
+				return dirPath, nil
+			}
+		}
+	}
+
+	log.Log.V(4).Infof("LimitedSwapManager: no cgroup named after container %s of pod %s/%s, looking it up by PID", containerID, pod.Namespace, pod.Name)
+	pid, err := runtime.ContainerPid(containerID)
+	if err != nil {
+		return "", err
+	}
+	return r.processCgroupPath(strconv.Itoa(pid))
+}
+
+// podCgroupPath returns the cgroup directory of the pod
+func (r *cgroupPathResolver) podCgroupPath(pod *v1.Pod) (string, error) {
+	for _, podCgroupPath := range podCgroupPaths(pod) {
+		dirPath := filepath.Join(r.cgroupRoot, podCgroupPath)
+		if _, err := os.Stat(dirPath); err == nil {
+			return dirPath, nil
+		}
+	}
+	return "", fmt.Errorf("could not find the cgroup of pod %s/%s", pod.Namespace, pod.Name)
+}
+
+// qosCgroupPath returns the cgroup directory of a QoS class, or of kubepods when qosCgroupName is empty
+func (r *cgroupPathResolver) qosCgroupPath(qosCgroupName string) (string, error) {
+	for _, qosCgroupPath := range []string{
+		systemdCgroupPath(kubepodsCgroupName, qosCgroupName),
+		filepath.Join(kubepodsCgroupName, qosCgroupName),
+	} {
+		dirPath := filepath.Join(r.cgroupRoot, qosCgroupPath)
+		if _, err := os.Stat(dirPath); err == nil {
+			return dirPath, nil
+		}
+	}
+	return "", fmt.Errorf("could not find the cgroup of %s", filepath.Join(kubepodsCgroupName, qosCgroupName))
+}
+
+// processCgroupPath returns the cgroup directory of a process
+func (r *cgroupPathResolver) processCgroupPath(pid string) (string, error) {
+	procCgroupBasePath := filepath.Join(r.procRoot, pid, "cgroup")
+	controllerPaths, err := cgroups.ParseCgroupFile(procCgroupBasePath)
+	if err != nil {
+		return "", err
+	}
+	path, ok := controllerPaths[r.controller]
+	if !ok {
+		return "", fmt.Errorf("could not get cgroup path")
+	}
+	return filepath.Join(r.cgroupRoot, path), nil
+}
+
+// podCgroupPaths returns the pod cgroup as named by the systemd driver, then by the cgroupfs driver,
+// e.g. kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod<uid>.slice and kubepods/burstable/pod<uid>
+func podCgroupPaths(pod *v1.Pod) []string {
+	podCgroupName := podCgroupNamePrefix + string(pod.UID)
+	// guaranteed pods are placed directly under kubepods
+	var qosCgroupName string
+	if qos := kubeapiqos.GetPodQOS(pod); qos != v1.PodQOSGuaranteed {
+		qosCgroupName = strings.ToLower(string(qos))
+	}
+
+	return []string{
+		systemdCgroupPath(kubepodsCgroupName, qosCgroupName, podCgroupName),
+		filepath.Join(kubepodsCgroupName, qosCgroupName, podCgroupName),
+	}
+}
+
+// systemdCgroupPath converts a cgroupfs path to nested systemd slices the way the kubelet does,
+// each slice is prefixed with its parents and dashes in the names are escaped with underscores
+func systemdCgroupPath(names ...string) string {
+	var slices []string
+	var prefix string
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		name = strings.ReplaceAll(name, "-", "_")
+		if prefix != "" {
+			name = prefix + "-" + name
+		}
+		slices = append(slices, name+systemdSliceSuffix)
+		prefix = name
+	}
+	return filepath.Join(slices...)
+}
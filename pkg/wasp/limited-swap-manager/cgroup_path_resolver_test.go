@@ -0,0 +1,124 @@
+package limited_swap_manager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	container_runtime "github.com/openshift-virtualization/wasp-agent/pkg/wasp/container-runtime"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	testPodUID      = "6d4e3c1a-8f2b-4a5c-9d7e-0b1c2d3e4f5a"
+	testContainerID = "0123abcd"
+	testPid         = 4242
+)
+
+// pidRuntime is a container runtime whose containers all run with the same PID
+type pidRuntime struct {
+	container_runtime.ContainerRuntime
+	pid int
+}
+
+func (r *pidRuntime) ContainerPid(_ string) (int, error) {
+	if r.pid == 0 {
+		return 0, fmt.Errorf("PID not found in container info")
+	}
+	return r.pid, nil
+}
+
+var _ = Describe("Cgroup path resolution", func() {
+	var (
+		resolver *cgroupPathResolver
+		runtime  *pidRuntime
+	)
+
+	BeforeEach(func() {
+		resolver = &cgroupPathResolver{
+			cgroupRoot: GinkgoT().TempDir(),
+			procRoot:   GinkgoT().TempDir(),
+		}
+		runtime = &pidRuntime{ContainerRuntime: container_runtime.NewCrio(container_runtime.CrioSocketPath, container_runtime.DefaultCallTimeout)}
+	})
+
+	createCgroup := func(path string) string {
+		dirPath := filepath.Join(resolver.cgroupRoot, path)
+		Expect(os.MkdirAll(dirPath, 0755)).To(Succeed())
+		return dirPath
+	}
+
+	podWithQOS := func(qos v1.PodQOSClass) *v1.Pod {
+		container := v1.Container{Name: "container"}
+		switch qos {
+		case v1.PodQOSGuaranteed:
+			container.Resources = v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("1Gi")},
+				Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("1Gi")},
+			}
+		case v1.PodQOSBurstable:
+			container.Resources = v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+			}
+		}
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns", UID: types.UID(testPodUID)},
+			Spec:       v1.PodSpec{Containers: []v1.Container{container}},
+		}
+	}
+
+	DescribeTable("should derive the cgroup from the pod and the container", func(runtimeContainer container_runtime.ContainerRuntime, qos v1.PodQOSClass, path string) {
+		runtime.ContainerRuntime = runtimeContainer
+		expected := createCgroup(path)
+
+		dirPath, err := resolver.containerCgroupPath(podWithQOS(qos), runtime, testContainerID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dirPath).To(Equal(expected))
+	},
+		Entry("CRI-O, systemd, burstable", container_runtime.NewCrio(container_runtime.CrioSocketPath, container_runtime.DefaultCallTimeout), v1.PodQOSBurstable,
+			"kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod6d4e3c1a_8f2b_4a5c_9d7e_0b1c2d3e4f5a.slice/crio-0123abcd.scope"),
+		Entry("CRI-O, systemd, best effort", container_runtime.NewCrio(container_runtime.CrioSocketPath, container_runtime.DefaultCallTimeout), v1.PodQOSBestEffort,
+			"kubepods.slice/kubepods-besteffort.slice/kubepods-besteffort-pod6d4e3c1a_8f2b_4a5c_9d7e_0b1c2d3e4f5a.slice/crio-0123abcd.scope"),
+		Entry("CRI-O, systemd, guaranteed", container_runtime.NewCrio(container_runtime.CrioSocketPath, container_runtime.DefaultCallTimeout), v1.PodQOSGuaranteed,
+			"kubepods.slice/kubepods-pod6d4e3c1a_8f2b_4a5c_9d7e_0b1c2d3e4f5a.slice/crio-0123abcd.scope"),
+		Entry("CRI-O, cgroupfs, burstable", container_runtime.NewCrio(container_runtime.CrioSocketPath, container_runtime.DefaultCallTimeout), v1.PodQOSBurstable,
+			"kubepods/burstable/pod6d4e3c1a-8f2b-4a5c-9d7e-0b1c2d3e4f5a/crio-0123abcd"),
+		Entry("containerd, systemd, burstable", container_runtime.NewContainerd(container_runtime.ContainerdSocketPath, container_runtime.DefaultCallTimeout), v1.PodQOSBurstable,
+			"kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod6d4e3c1a_8f2b_4a5c_9d7e_0b1c2d3e4f5a.slice/cri-containerd-0123abcd.scope"),
+		Entry("containerd, cgroupfs, guaranteed", container_runtime.NewContainerd(container_runtime.ContainerdSocketPath, container_runtime.DefaultCallTimeout), v1.PodQOSGuaranteed,
+			"kubepods/pod6d4e3c1a-8f2b-4a5c-9d7e-0b1c2d3e4f5a/0123abcd"),
+	)
+
+	It("should fall back to the cgroup of the container process", func() {
+		runtime.pid = testPid
+		expected := createCgroup("custom.slice/container")
+		procDir := filepath.Join(resolver.procRoot, fmt.Sprint(testPid))
+		Expect(os.MkdirAll(procDir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(procDir, "cgroup"), []byte("0::/custom.slice/container\n"), 0644)).To(Succeed())
+
+		dirPath, err := resolver.containerCgroupPath(podWithQOS(v1.PodQOSBurstable), runtime, testContainerID)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dirPath).To(Equal(expected))
+	})
+
+	It("should find the pod cgroup", func() {
+		expected := createCgroup("kubepods/burstable/pod6d4e3c1a-8f2b-4a5c-9d7e-0b1c2d3e4f5a")
+		dirPath, err := resolver.podCgroupPath(podWithQOS(v1.PodQOSBurstable))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(dirPath).To(Equal(expected))
+
+		_, err = resolver.podCgroupPath(podWithQOS(v1.PodQOSGuaranteed))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should fail when neither the cgroup nor the PID are found", func() {
+		_, err := resolver.containerCgroupPath(podWithQOS(v1.PodQOSBurstable), runtime, testContainerID)
+		Expect(err).To(HaveOccurred())
+	})
+})
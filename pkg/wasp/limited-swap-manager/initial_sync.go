@@ -0,0 +1,76 @@
+package limited_swap_manager
+
+import (
+	"sync"
+)
+
+// initialSyncMaxRetries is the number of failed reconciliations after which a pod no longer delays the initial sync
+const initialSyncMaxRetries = 10
+
+// initialSync tracks the first reconciliation of the pods found on the node when the LimitedSwapManager starts
+type initialSync struct {
+	lock    sync.Mutex
+	pending map[string]struct{}
+	// failed are the pods that were given up on, their reconciliation is still retried
+	failed  map[string]struct{}
+	started bool
+	done    chan struct{}
+}
+
+func newInitialSync() *initialSync {
+	return &initialSync{failed: map[string]struct{}{}, done: make(chan struct{})}
+}
+
+// start records the pods to reconcile, the initial sync is done right away when there are none
+func (s *initialSync) start(keys []string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	s.pending = make(map[string]struct{}, len(keys))
+	for _, key := range keys {
+		s.pending[key] = struct{}{}
+	}
+	s.closeIfDone()
+}
+
+// reconciled marks a pod as reconciled, it is only called once all the swap limits of the pod were set
+func (s *initialSync) reconciled(key string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.failed, key)
+	if !s.started || len(s.pending) == 0 {
+		return
+	}
+	delete(s.pending, key)
+	s.closeIfDone()
+}
+
+// gaveUp stops waiting for a pod whose reconciliation keeps failing, so that it does not delay the initial sync forever.
+// It returns whether the pod was still pending.
+func (s *initialSync) gaveUp(key string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, pending := s.pending[key]; !pending {
+		return false
+	}
+	delete(s.pending, key)
+	s.failed[key] = struct{}{}
+	s.closeIfDone()
+	return true
+}
+
+// failures returns the number of pods that were given up on and were not reconciled since
+func (s *initialSync) failures() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.failed)
+}
+
+func (s *initialSync) closeIfDone() {
+	if len(s.pending) == 0 {
+		close(s.done)
+	}
+}
@@ -0,0 +1,103 @@
+package limited_swap_manager
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var _ = Describe("Initial sync", func() {
+	It("should be done once all the pods found at startup are reconciled", func() {
+		sync := newInitialSync()
+		sync.reconciled("ns/a")
+		Expect(sync.done).ToNot(BeClosed())
+
+		sync.start([]string{"ns/a", "ns/b"})
+		sync.reconciled("ns/a")
+		sync.reconciled("ns/c")
+		Expect(sync.done).ToNot(BeClosed())
+
+		sync.reconciled("ns/b")
+		Expect(sync.done).To(BeClosed())
+		sync.reconciled("ns/b")
+	})
+
+	It("should not wait for the pods it gave up on", func() {
+		sync := newInitialSync()
+		Expect(sync.gaveUp("ns/a")).To(BeFalse())
+
+		sync.start([]string{"ns/a", "ns/b"})
+		sync.reconciled("ns/a")
+		Expect(sync.gaveUp("ns/b")).To(BeTrue())
+		Expect(sync.gaveUp("ns/b")).To(BeFalse())
+		Expect(sync.done).To(BeClosed())
+		Expect(sync.failures()).To(Equal(1))
+
+		sync.reconciled("ns/b")
+		Expect(sync.failures()).To(BeZero())
+	})
+
+	It("should be done right away when there are no pods", func() {
+		sync := newInitialSync()
+		sync.start(nil)
+		Expect(sync.done).To(BeClosed())
+	})
+})
+
+var _ = Describe("Initial sync of the LimitedSwapManager", func() {
+	It("should not be done while the reconciliation of a pod fails", func() {
+		policy, err := NewSwapPolicy(DefaultSwapPolicy, SwapPolicyOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		Expect(podIndexer.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}})).To(Succeed())
+		lsm := &LimitedSwapManager{
+			podLister:          v1lister.NewPodLister(podIndexer),
+			podQueue:           workqueue.NewRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Millisecond)),
+			swapPolicyResolver: newSwapPolicyResolver(nil, nil, policy),
+			initialSync:        newInitialSync(),
+		}
+		DeferCleanup(lsm.podQueue.ShutDown)
+		lsm.startInitialSync()
+		lsm.podQueue.Add("ns/pod")
+
+		// the capacity of the node is not known yet
+		Expect(lsm.Execute()).To(BeTrue())
+		Expect(lsm.InitialSyncDone()).ToNot(BeClosed())
+
+		lsm.nodeCapacity.set(NodeCapacity{Memory: 1024, Swap: 1024})
+		Expect(lsm.Execute()).To(BeTrue())
+		Expect(lsm.InitialSyncDone()).To(BeClosed())
+	})
+
+	It("should be done once the reconciliation of a pod failed too many times", func() {
+		policy, err := NewSwapPolicy(DefaultSwapPolicy, SwapPolicyOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		Expect(podIndexer.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}})).To(Succeed())
+		lsm := &LimitedSwapManager{
+			podLister:          v1lister.NewPodLister(podIndexer),
+			podQueue:           workqueue.NewRateLimitingQueue(workqueue.NewItemExponentialFailureRateLimiter(time.Millisecond, time.Millisecond)),
+			swapPolicyResolver: newSwapPolicyResolver(nil, nil, policy),
+			initialSync:        newInitialSync(),
+		}
+		DeferCleanup(lsm.podQueue.ShutDown)
+		lsm.startInitialSync()
+		lsm.podQueue.Add("ns/pod")
+
+		// the capacity of the node is never known
+		for i := 0; i < initialSyncMaxRetries; i++ {
+			Expect(lsm.Execute()).To(BeTrue())
+			Expect(lsm.InitialSyncDone()).ToNot(BeClosed())
+		}
+		Expect(lsm.Execute()).To(BeTrue())
+		Expect(lsm.InitialSyncDone()).To(BeClosed())
+		Expect(lsm.InitialSyncFailures()).To(Equal(1))
+	})
+})
@@ -0,0 +1,81 @@
+package limited_swap_manager
+
+import (
+	"strings"
+
+	"github.com/openshift-virtualization/wasp-agent/pkg/log"
+	"github.com/openshift-virtualization/wasp-agent/pkg/monitoring/metrics"
+	v1 "k8s.io/api/core/v1"
+)
+
+// KubepodsSwapLimit reserves swap for the system services, e.g. the kubelet and the container runtime,
+// by computing the swap of the containers against the rest of the node swap and optionally capping all the pods to it
+type KubepodsSwapLimit struct {
+	// SystemReserve is the swap in bytes kept for the system services
+	SystemReserve int64
+	// Kubepods caps the swap of the kubepods cgroup
+	Kubepods bool
+	// Burstable also caps the swap of the burstable QoS cgroup
+	Burstable bool
+}
+
+// podsSwap returns the swap the pods share
+func (k KubepodsSwapLimit) podsSwap(nodeSwap int64) int64 {
+	if nodeSwap <= k.SystemReserve {
+		return 0
+	}
+	return nodeSwap - k.SystemReserve
+}
+
+// qosCgroupNames returns the QoS cgroups whose swap is capped, the empty name is kubepods itself
+func (k KubepodsSwapLimit) qosCgroupNames() []string {
+	var names []string
+	if k.Kubepods {
+		names = append(names, "")
+	}
+	if k.Burstable {
+		names = append(names, strings.ToLower(string(v1.PodQOSBurstable)))
+	}
+	return names
+}
+
+func qosCgroupDescription(qosCgroupName string) string {
+	if qosCgroupName == "" {
+		return kubepodsCgroupName
+	}
+	return qosCgroupName
+}
+
+// setKubepodsSwapLimit caps the swap of all the pods to the swap they share,
+// it is applied on every capacity check so that the cap follows the node swap
+func (lsm *LimitedSwapManager) setKubepodsSwapLimit(podsSwap int64) {
+	for _, qosCgroupName := range lsm.kubepodsSwapLimit.qosCgroupNames() {
+		cgroup := qosCgroupDescription(qosCgroupName)
+		dirPath, err := lsm.cgroupPathResolver.qosCgroupPath(qosCgroupName)
+		if err == nil {
+			_, _, err = lsm.applySwapLimit(cgroup+" cgroup", dirPath, podsSwap)
+		}
+		if err != nil {
+			log.Log.Errorf("LimitedSwapManager: couldn't cap the swap of the %s cgroup: %v", cgroup, err)
+			metrics.IncReconcileError(metrics.ReasonCgroupWriteFailed)
+			continue
+		}
+		metrics.SetQoSSwapMax(cgroup, podsSwap)
+	}
+}
+
+// restoreKubepodsSwapLimit removes the cap of the swap of all the pods
+func (lsm *LimitedSwapManager) restoreKubepodsSwapLimit() []error {
+	var errs []error
+	for _, qosCgroupName := range lsm.kubepodsSwapLimit.qosCgroupNames() {
+		cgroup := qosCgroupDescription(qosCgroupName)
+		dirPath, err := lsm.cgroupPathResolver.qosCgroupPath(qosCgroupName)
+		if err == nil {
+			_, _, err = lsm.applySwapLimit(cgroup+" cgroup", dirPath, UnlimitedSwap)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
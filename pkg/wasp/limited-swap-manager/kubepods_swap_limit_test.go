@@ -0,0 +1,71 @@
+package limited_swap_manager
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Kubepods swap limit", func() {
+	const gi = 1024 * 1024 * 1024
+
+	var (
+		lsm        *LimitedSwapManager
+		cgroupRoot string
+	)
+
+	readSwapMax := func(cgroupPath string) string {
+		swapMax, err := os.ReadFile(filepath.Join(cgroupRoot, cgroupPath, swapMaxFile))
+		Expect(err).ToNot(HaveOccurred())
+		return string(swapMax)
+	}
+
+	BeforeEach(func() {
+		cgroupRoot = GinkgoT().TempDir()
+		lsm = &LimitedSwapManager{
+			cgroupPathResolver: &cgroupPathResolver{cgroupRoot: cgroupRoot},
+			cgroupMode:         cgroupV2,
+			kubepodsSwapLimit:  KubepodsSwapLimit{SystemReserve: 2 * gi, Kubepods: true, Burstable: true},
+		}
+	})
+
+	DescribeTable("should reserve swap for the system", func(nodeSwap, expected int64) {
+		Expect(lsm.kubepodsSwapLimit.podsSwap(nodeSwap)).To(Equal(expected))
+	},
+		Entry("when the node has more swap than the reserve", int64(8*gi), int64(6*gi)),
+		Entry("when the node has less swap than the reserve", int64(1*gi), int64(0)),
+	)
+
+	It("should cap the swap of the kubepods and burstable cgroups of the systemd driver", func() {
+		writeCgroupFiles(filepath.Join(cgroupRoot, "kubepods.slice"), map[string]string{swapMaxFile: "max\n"})
+		writeCgroupFiles(filepath.Join(cgroupRoot, "kubepods.slice", "kubepods-burstable.slice"), map[string]string{swapMaxFile: "max\n"})
+
+		lsm.setKubepodsSwapLimit(6 * gi)
+		Expect(readSwapMax("kubepods.slice")).To(Equal("6442450944"))
+		Expect(readSwapMax("kubepods.slice/kubepods-burstable.slice")).To(Equal("6442450944"))
+
+		Expect(lsm.restoreKubepodsSwapLimit()).To(BeEmpty())
+		Expect(readSwapMax("kubepods.slice")).To(Equal("max"))
+		Expect(readSwapMax("kubepods.slice/kubepods-burstable.slice")).To(Equal("max"))
+	})
+
+	It("should cap the swap of the kubepods cgroup of the cgroupfs driver", func() {
+		lsm.kubepodsSwapLimit.Burstable = false
+		writeCgroupFiles(filepath.Join(cgroupRoot, "kubepods"), map[string]string{swapMaxFile: "max\n"})
+		writeCgroupFiles(filepath.Join(cgroupRoot, "kubepods", "burstable"), map[string]string{swapMaxFile: "max\n"})
+
+		lsm.setKubepodsSwapLimit(6 * gi)
+		Expect(readSwapMax("kubepods")).To(Equal("6442450944"))
+		Expect(readSwapMax("kubepods/burstable")).To(Equal("max\n"))
+	})
+
+	It("should not touch the cgroups when no cap is configured", func() {
+		lsm.kubepodsSwapLimit = KubepodsSwapLimit{SystemReserve: 2 * gi}
+		writeCgroupFiles(filepath.Join(cgroupRoot, "kubepods.slice"), map[string]string{swapMaxFile: "max\n"})
+
+		lsm.setKubepodsSwapLimit(6 * gi)
+		Expect(readSwapMax("kubepods.slice")).To(Equal("max\n"))
+	})
+})
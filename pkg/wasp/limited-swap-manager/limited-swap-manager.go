@@ -1,114 +1,240 @@
 package limited_swap_manager
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
-	"github.com/opencontainers/runc/libcontainer/cgroups"
 	"github.com/openshift-virtualization/wasp-agent/pkg/client"
 	"github.com/openshift-virtualization/wasp-agent/pkg/log"
-	"github.com/shirou/gopsutil/mem"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"github.com/openshift-virtualization/wasp-agent/pkg/monitoring/metrics"
+	container_runtime "github.com/openshift-virtualization/wasp-agent/pkg/wasp/container-runtime"
 	v1 "k8s.io/api/core/v1"
 	kapierrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	v1lister "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
-	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
-	kubeapiqos "k8s.io/kubernetes/pkg/apis/core/v1/helper/qos"
-	kubelettypes "k8s.io/kubernetes/pkg/kubelet/types"
-	"path/filepath"
-	"strconv"
+	"reflect"
 	"strings"
+	"sync"
 	"time"
 )
 
 type enqueueState string
 
 const (
-	Immediate      enqueueState = "Immediate"
-	Forget         enqueueState = "Forget"
-	BackOff        enqueueState = "BackOff"
-	cgroupPathBase              = "/host/sys/fs/cgroup"
+	Immediate         enqueueState = "Immediate"
+	Forget            enqueueState = "Forget"
+	BackOff           enqueueState = "BackOff"
+	WaitForEvent      enqueueState = "WaitForEvent"
+	DefaultCgroupRoot              = "/host/sys/fs/cgroup"
+
+	containerRuntimeHealthCheckInterval = 10 * time.Second
 )
 
+// Configuration is the configuration of the LimitedSwapManager that can be changed at runtime
+type Configuration struct {
+	// SwapPolicy applies to the pods that are not selected by a SwapPolicy object
+	SwapPolicy SwapPolicy
+	// PodSwapLimit also caps the swap of the pod cgroups
+	PodSwapLimit bool
+	// Inclusions select the pods that get swap, all the pods are eligible for swap when empty
+	Inclusions PodRules
+	// Exclusions select the pods that never get swap, they take precedence over Inclusions
+	Exclusions PodRules
+	// Throttling sets the soft limits of the containers that get swap
+	Throttling Throttling
+}
+
 type LimitedSwapManager struct {
-	podInformer    cache.SharedIndexInformer
-	podLister      v1lister.PodLister
-	podQueue       workqueue.RateLimitingInterface
-	waspCli        client.WaspClient
-	swapCapacity   uint64
-	memoryCapacity uint64
-	nodeName       string
-	stop           <-chan struct{}
+	podInformer          cache.SharedIndexInformer
+	vmiStore             cache.Store
+	podLister            v1lister.PodLister
+	podQueue             workqueue.RateLimitingInterface
+	waspCli              client.WaspClient
+	swapPolicyResolver   *swapPolicyResolver
+	managedContainers    *managedContainers
+	containerRuntimes    *container_runtime.ContainerRuntimes
+	cgroupPathResolver   *cgroupPathResolver
+	cgroupMode           cgroupMode
+	configLock           sync.RWMutex
+	exclusionReasons     sync.Map
+	annotationErrors     sync.Map
+	config               Configuration
+	dryRun               bool
+	swapUsageInterval    time.Duration
+	resyncPeriod         time.Duration
+	capacityInterval     time.Duration
+	memoryCapacitySource MemoryCapacitySource
+	kubepodsSwapLimit    KubepodsSwapLimit
+	nodeCapacity         nodeCapacity
+	readNodeCapacity     func() (NodeCapacity, error)
+	initialSync          *initialSync
+	nodeName             string
+	recorder             record.EventRecorder
+	stop                 <-chan struct{}
 }
 
+// NewLimitedSwapManager creates a LimitedSwapManager.
+// swapPolicyInformer and namespaceInformer are nil when the SwapPolicy CRD is not installed,
+// in which case swapPolicy applies to all pods.
 func NewLimitedSwapManager(waspCli client.WaspClient,
 	podInformer cache.SharedIndexInformer,
+	swapPolicyInformer cache.SharedIndexInformer,
+	namespaceInformer cache.SharedIndexInformer,
+	vmiInformer cache.SharedIndexInformer,
 	nodeName string,
+	config Configuration,
+	memoryCapacitySource MemoryCapacitySource,
+	kubepodsSwapLimit KubepodsSwapLimit,
+	cgroupRoot string,
+	dryRun bool,
+	recorder record.EventRecorder,
+	containerRuntimes *container_runtime.ContainerRuntimes,
+	swapUsageInterval time.Duration,
+	capacityInterval time.Duration,
+	resyncPeriod time.Duration,
+	rateLimiter workqueue.RateLimiter,
 	stop <-chan struct{},
-) *LimitedSwapManager {
-	swap, err := mem.SwapMemory()
-	if err != nil {
-		panic(fmt.Sprintf("Error fetching swap memory: %v", err.Error()))
-	}
-	virtualMem, err := mem.VirtualMemory()
+) (*LimitedSwapManager, error) {
+	mode, err := detectCgroupMode(cgroupRoot)
 	if err != nil {
-		panic(fmt.Sprintf("Error fetching virtualMem memory: %v", err))
+		return nil, err
 	}
 	cgroupManager := LimitedSwapManager{
-		podInformer:    podInformer,
-		podLister:      v1lister.NewPodLister(podInformer.GetIndexer()),
-		waspCli:        waspCli,
-		nodeName:       nodeName,
-		podQueue:       workqueue.NewRateLimitingQueueWithConfig(workqueue.DefaultControllerRateLimiter(), workqueue.RateLimitingQueueConfig{Name: "pdo-queue-for-cgroup-manager"}),
-		stop:           stop,
-		swapCapacity:   swap.Total,
-		memoryCapacity: virtualMem.Total,
+		podInformer:          podInformer,
+		podLister:            v1lister.NewPodLister(podInformer.GetIndexer()),
+		waspCli:              waspCli,
+		nodeName:             nodeName,
+		swapPolicyResolver:   newSwapPolicyResolver(swapPolicyInformer, namespaceInformer, config.SwapPolicy),
+		managedContainers:    newManagedContainers(),
+		containerRuntimes:    containerRuntimes,
+		cgroupPathResolver:   newCgroupPathResolver(cgroupRoot, mode),
+		cgroupMode:           mode,
+		dryRun:               dryRun,
+		initialSync:          newInitialSync(),
+		config:               config,
+		swapUsageInterval:    swapUsageInterval,
+		capacityInterval:     capacityInterval,
+		memoryCapacitySource: memoryCapacitySource,
+		kubepodsSwapLimit:    kubepodsSwapLimit,
+		readNodeCapacity:     newNodeCapacityReader(memoryCapacitySource, waspCli.CoreV1().Nodes(), nodeName),
+		resyncPeriod:         resyncPeriod,
+		recorder:             recorder,
+		podQueue:             workqueue.NewRateLimitingQueueWithConfig(rateLimiter, workqueue.RateLimitingQueueConfig{Name: "pdo-queue-for-cgroup-manager"}),
+		stop:                 stop,
 	}
+	// pods are not reconciled until the capacity of the node is known, it is read again periodically when this fails
+	cgroupManager.detectNodeCapacity()
 
 	_, err = cgroupManager.podInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    cgroupManager.enqueuePod,
 		UpdateFunc: cgroupManager.updatePod,
-		DeleteFunc: cgroupManager.createPod,
+		DeleteFunc: cgroupManager.enqueuePod,
 	})
 	if err != nil {
-		panic("something is wrong")
+		return nil, err
 	}
-	return &cgroupManager
-}
 
-func (lsm *LimitedSwapManager) updatePod(old, curr interface{}) {
-	curPod := curr.(*v1.Pod)
-	oldPod := old.(*v1.Pod)
-	if oldPod.Spec.NodeName != lsm.nodeName && curPod.Spec.NodeName == lsm.nodeName {
-		key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(curPod)
+	if swapPolicyInformer != nil {
+		_, err = swapPolicyInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    cgroupManager.swapPolicyChanged,
+			UpdateFunc: func(_, curr interface{}) { cgroupManager.swapPolicyChanged(curr) },
+			DeleteFunc: cgroupManager.swapPolicyChanged,
+		})
 		if err != nil {
-			log.Log.Errorf("LimitedSwapManager: %v", err)
-			return
+			return nil, err
 		}
-		lsm.podQueue.Add(key)
 	}
+	if swapPolicyInformer != nil && namespaceInformer != nil {
+		_, err = namespaceInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    cgroupManager.enqueueNamespacePods,
+			UpdateFunc: cgroupManager.namespaceUpdated,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if vmiInformer != nil {
+		cgroupManager.vmiStore = vmiInformer.GetStore()
+		_, err = vmiInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    cgroupManager.vmiChanged,
+			UpdateFunc: func(_, curr interface{}) { cgroupManager.vmiChanged(curr) },
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &cgroupManager, nil
+}
 
-	return
+// SetConfiguration applies a new configuration and reconciles all the pods on the node with it
+func (lsm *LimitedSwapManager) SetConfiguration(config Configuration) {
+	lsm.configLock.Lock()
+	lsm.config = config
+	lsm.configLock.Unlock()
+	lsm.swapPolicyResolver.setDefaultPolicy(config.SwapPolicy)
+	lsm.enqueueAllPods()
 }
 
-func (lsm *LimitedSwapManager) createPod(podObj interface{}) {
-	pod := podObj.(*v1.Pod)
-	if pod.Spec.NodeName == lsm.nodeName {
-		key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(pod)
-		if err != nil {
-			log.Log.Errorf("LimitedSwapManager: %v", err)
-			return
-		}
-		lsm.podQueue.Add(key)
+func (lsm *LimitedSwapManager) configuration() Configuration {
+	lsm.configLock.RLock()
+	defer lsm.configLock.RUnlock()
+	return lsm.config
+}
+
+// swapPolicyChanged reconciles all the pods on the node, since any of them may be selected by the changed SwapPolicy
+func (lsm *LimitedSwapManager) swapPolicyChanged(_ interface{}) {
+	lsm.enqueueAllPods()
+}
+
+// namespaceUpdated reconciles the pods of a namespace when its labels change,
+// since they may now be selected by another SwapPolicy
+func (lsm *LimitedSwapManager) namespaceUpdated(old, curr interface{}) {
+	oldNamespace, ok := old.(*v1.Namespace)
+	if !ok {
+		return
+	}
+	namespace, ok := curr.(*v1.Namespace)
+	if !ok || reflect.DeepEqual(oldNamespace.Labels, namespace.Labels) {
+		return
 	}
-	return
+	lsm.enqueueNamespacePods(namespace)
 }
+
+func (lsm *LimitedSwapManager) enqueueNamespacePods(obj interface{}) {
+	namespace, ok := obj.(*v1.Namespace)
+	if !ok {
+		return
+	}
+	pods, err := lsm.podLister.Pods(namespace.Name).List(labels.Everything())
+	if err != nil {
+		log.Log.Errorf("LimitedSwapManager: %v", err)
+		return
+	}
+	for _, pod := range pods {
+		lsm.enqueuePod(pod)
+	}
+}
+
+func (lsm *LimitedSwapManager) updatePod(old, curr interface{}) {
+	if podNeedsReconcile(old.(*v1.Pod), curr.(*v1.Pod)) {
+		lsm.enqueuePod(curr)
+	}
+}
+
+// enqueuePod handles pod additions and deletions, the pod informer only watches the pods of the node.
+// Deleted pods are reconciled so that their metrics and managed containers are cleaned up.
+func (lsm *LimitedSwapManager) enqueuePod(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		log.Log.Errorf("LimitedSwapManager: %v", err)
+		return
+	}
+	lsm.podQueue.Add(key)
+}
+
 func (lsm *LimitedSwapManager) runWorker() {
 	for lsm.Execute() {
 	}
@@ -117,19 +243,14 @@ func (lsm *LimitedSwapManager) runWorker() {
 func (lsm *LimitedSwapManager) enqueueAllPods() {
 	pods, err := lsm.podLister.List(labels.Everything())
 	if err != nil {
-		log.Log.Errorf(err.Error())
+		log.Log.Errorf("LimitedSwapManager: %v", err)
 		return
 	}
 	for _, p := range pods {
-		if p.Spec.NodeName == lsm.nodeName {
-			key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(p)
-			if err != nil {
-				log.Log.Errorf("LimitedSwapManager: %v", err)
-				return
-			}
-			lsm.podQueue.Add(key)
-		}
+		lsm.enqueuePod(p)
 	}
+	metrics.SetLastFullResync(time.Now())
+	metrics.SetWorkQueueDepth(lsm.podQueue.Len())
 }
 
 func (lsm *LimitedSwapManager) Execute() bool {
@@ -138,15 +259,22 @@ func (lsm *LimitedSwapManager) Execute() bool {
 		return false
 	}
 	defer lsm.podQueue.Done(key)
+	defer func() { metrics.SetWorkQueueDepth(lsm.podQueue.Len()) }()
 
 	err, enqueueState := lsm.execute(key.(string))
 	if err != nil {
-		log.Log.Infof(fmt.Sprintf("RQController: Error with key: %v err: %v", key, err))
+		log.Log.Infof("LimitedSwapManager: Error with key: %v err: %v", key, err)
+	}
+	if err == nil && enqueueState == Forget {
+		lsm.initialSync.reconciled(key.(string))
+	} else if enqueueState == BackOff && lsm.podQueue.NumRequeues(key) >= initialSyncMaxRetries && lsm.initialSync.gaveUp(key.(string)) {
+		log.Log.Errorf("LimitedSwapManager: pod %v failed to be reconciled %d times, the initial sync no longer waits for it", key, initialSyncMaxRetries)
 	}
 	switch enqueueState {
 	case BackOff:
 		lsm.podQueue.AddRateLimited(key)
-	case Forget:
+	case Forget, WaitForEvent:
+		// a pod waiting for an event is not reconciled yet, the informers enqueue it again
 		lsm.podQueue.Forget(key)
 	case Immediate:
 		lsm.podQueue.Add(key)
@@ -155,153 +283,281 @@ func (lsm *LimitedSwapManager) Execute() bool {
 	return true
 }
 
+// startInitialSync records the pods found on the node, InitialSyncDone is closed once all of them are reconciled
+func (lsm *LimitedSwapManager) startInitialSync() {
+	pods, err := lsm.podLister.List(labels.Everything())
+	if err != nil {
+		log.Log.Errorf("LimitedSwapManager: %v", err)
+	}
+	keys := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		keys = append(keys, pod.Namespace+"/"+pod.Name)
+	}
+	lsm.initialSync.start(keys)
+}
+
+// InitialSyncDone is closed once all the pods found on the node at startup were reconciled successfully,
+// or failed to be reconciled initialSyncMaxRetries times
+func (lsm *LimitedSwapManager) InitialSyncDone() <-chan struct{} {
+	return lsm.initialSync.done
+}
+
+// InitialSyncFailures returns the number of pods found on the node at startup whose swap limits could not be set yet
+func (lsm *LimitedSwapManager) InitialSyncFailures() int {
+	return lsm.initialSync.failures()
+}
+
+// SwapCapacity returns the swap the pods share in bytes, the node swap without the system reserve, 0 when it is unknown
+func (lsm *LimitedSwapManager) SwapCapacity() uint64 {
+	capacity, _ := lsm.nodeCapacity.get()
+	return uint64(capacity.Swap)
+}
+
 func (lsm *LimitedSwapManager) Run(threadiness int) {
 	defer utilruntime.HandleCrash()
-	log.Log.Infof("Starting LimitedSwapManager")
+	log.Log.Infof("Starting LimitedSwapManager with %d workers", threadiness)
 	defer log.Log.Infof("Shutting down LimitedSwapManager")
 	defer lsm.podQueue.ShutDown()
+	defer lsm.containerRuntimes.Close()
 
+	lsm.startInitialSync()
 	for i := 0; i < threadiness; i++ {
 		go wait.Until(lsm.runWorker, time.Second, lsm.stop)
-		go wait.Until(lsm.enqueueAllPods, metav1.Duration{Duration: 20 * time.Second}.Duration, lsm.stop)
 	}
+	go wait.Until(lsm.enqueueAllPods, lsm.resyncPeriod, lsm.stop)
+	go wait.Until(lsm.reconcileNodeCapacity, lsm.capacityInterval, lsm.stop)
+	if lsm.cgroupMode == cgroupV2 {
+		go wait.Until(lsm.collectSwapUsage, lsm.swapUsageInterval, lsm.stop)
+	} else {
+		log.Log.Infof("LimitedSwapManager: swap usage metrics are only collected, and soft limits only set, on cgroup v2")
+	}
+	go wait.Until(lsm.containerRuntimes.HealthCheck, containerRuntimeHealthCheckInterval, lsm.stop)
 
 	<-lsm.stop
 }
 
 func (lsm *LimitedSwapManager) execute(key string) (error, enqueueState) {
+	metrics.IncReconcile()
 	namespace, name, err := cache.SplitMetaNamespaceKey(key)
 	pod, err := lsm.podLister.Pods(namespace).Get(name)
 	if kapierrors.IsNotFound(err) {
+		metrics.DeletePodMetrics(namespace, name)
+		lsm.managedContainers.removePod(namespace, name)
+		lsm.exclusionReasons.Delete(key)
+		lsm.annotationErrors.Delete(key)
 		return nil, Forget
 	} else if err != nil {
-		log.Log.Errorf(err.Error())
+		log.Log.Errorf("LimitedSwapManager: %v", err)
+		return err, BackOff
+	}
+	capacity, known := lsm.nodeCapacity.get()
+	if !known {
+		return fmt.Errorf("the capacity of the node is unknown"), BackOff
+	}
+	swapConfig, err := lsm.swapPolicyResolver.resolve(pod)
+	if err != nil {
+		log.Log.Errorf("LimitedSwapManager: %v", err)
+		return err, BackOff
+	}
+	swapConfig, found, err := lsm.virtLauncherSwapConfig(pod, swapConfig)
+	if err != nil {
 		return err, BackOff
+	} else if !found {
+		log.Log.Infof("LimitedSwapManager: the VMI of virt-launcher pod %s is not known yet, its containers get no swap until it is", key)
+	}
+	config := lsm.configuration()
+	exclusionReason := swapExclusionReason(pod, swapConfig, config)
+	lsm.recordSwapExclusion(key, pod, exclusionReason)
+	// the containers of a virt-launcher pod get no swap until the guest memory of its VMI is known
+	setAllContainersSwapToZero := exclusionReason != "" || !found
+	overrides, annotationErrs := parseSwapOverrides(pod)
+	lsm.recordAnnotationErrors(key, pod, annotationErrs)
+
+	// failed is set when a swap limit of the pod could not be set, the pod is then reconciled again with a back off
+	failed := false
+	if config.PodSwapLimit || overrides.podLimit != nil {
+		failed = !lsm.setPodSwapLimit(key, pod, capacity, swapConfig, overrides, setAllContainersSwapToZero)
 	}
-	podQos := kubeapiqos.GetPodQOS(pod)
-	setAllContainersSwapToZero := podQos != v1.PodQOSBurstable || kubelettypes.IsCriticalPod(pod)
 
 	for _, container := range append(pod.Spec.Containers, pod.Spec.InitContainers...) {
+		// containers that are not running yet are reconciled when their status changes
 		containerState, exist := getContainerState(pod, container)
-		if !exist || containerState.Waiting != nil || containerState.Running == nil {
-			lsm.podQueue.AddRateLimited(key)
+		if exist && containerState.Terminated != nil {
 			continue
-		} else if containerState.Terminated != nil {
+		} else if !exist || containerState.Running == nil {
+			metrics.IncReconcileSkip(metrics.ReasonContainerNotStarted)
 			continue
 		}
 
-		containerUID, err := getContainerUID(pod, container)
+		containerID, err := getContainerID(pod, container)
 		if err != nil {
-			lsm.podQueue.AddRateLimited(key)
+			metrics.IncReconcileError(metrics.ReasonContainerNotRunning)
+			failed = true
 			continue
 		}
 
-		dirPath, err := getContainerCgroupPath(containerUID)
+		runtime, containerUID, err := lsm.containerRuntimes.ForContainerID(containerID)
 		if err != nil {
-			log.Log.Errorf(err.Error())
-			lsm.podQueue.AddRateLimited(key)
+			log.Log.Errorf("LimitedSwapManager: %v", err)
+			metrics.IncReconcileError(metrics.ReasonCRILookupFailed)
+			failed = true
+			continue
 		}
-		containerDoesNotRequestMemory := container.Resources.Requests.Memory().IsZero() && container.Resources.Limits.Memory().IsZero()
-		memoryRequestEqualsToLimit := container.Resources.Requests.Memory().Cmp(*container.Resources.Limits.Memory()) == 0
-		if containerDoesNotRequestMemory || memoryRequestEqualsToLimit || setAllContainersSwapToZero {
-			err := setSwapLimit(dirPath, 0)
-			if err != nil {
-				log.Log.Infof("LimitSwapManager: couldn't set swap limit: %v", err.Error())
-				lsm.podQueue.AddRateLimited(key)
-			}
+
+		dirPath, err := lsm.cgroupPathResolver.containerCgroupPath(pod, runtime, containerUID)
+		if err != nil {
+			log.Log.Errorf("LimitedSwapManager: %v", err)
+			metrics.IncReconcileError(metrics.ReasonCRILookupFailed)
+			failed = true
 			continue
 		}
-		containerMemoryRequest := container.Resources.Requests.Memory()
-		swapLimit := calcSwapForBurstablePods(containerMemoryRequest.Value(), int64(lsm.memoryCapacity), int64(lsm.swapCapacity))
-		err = setSwapLimit(dirPath, swapLimit)
+
+		managed, wasManaged := lsm.managedContainers.get(containerUID)
+		swapLimit := lsm.containerSwapLimit(&container, capacity, swapConfig, overrides, setAllContainersSwapToZero)
+		current, applied, err := lsm.applySwapLimit("container "+key+"/"+container.Name, dirPath, swapLimit)
 		if err != nil {
 			log.Log.Infof("LimitSwapManager: couldn't set swap limit: %v", err.Error())
-			lsm.podQueue.AddRateLimited(key)
+			metrics.IncReconcileError(metrics.ReasonCgroupWriteFailed)
+			failed = true
 			continue
 		}
+		if lsm.dryRun {
+			// the cgroup keeps its swap limit, which is not a drift
+			applied = current
+			currentSwapLimit, err := swapLimitFromValue(lsm.cgroupMode, dirPath, current)
+			if err == nil {
+				metrics.SetContainerSwapMaxCurrent(pod.Namespace, pod.Name, container.Name, currentSwapLimit)
+			}
+		} else if wasManaged && managed.swapLimitValue != current {
+			log.Log.Infof("LimitedSwapManager: swap limit of container %s/%s/%s was changed outside of the agent from %d to %d",
+				pod.Namespace, pod.Name, container.Name, managed.swapLimitValue, current)
+			metrics.IncSwapLimitDrift()
+		}
+		metrics.SetContainerSwapMax(pod.Namespace, pod.Name, container.Name, swapLimit)
+
+		throttled := managed.softLimits
+		if lsm.cgroupMode == cgroupV2 {
+			throttled, err = lsm.applyThrottling("container "+key+"/"+container.Name, dirPath, &container, swapLimit, config.Throttling, managed.softLimits)
+			if err != nil {
+				log.Log.Infof("LimitSwapManager: couldn't set soft limits: %v", err.Error())
+				metrics.IncReconcileError(metrics.ReasonCgroupWriteFailed)
+				failed = true
+			}
+			setContainerSoftLimitMetrics(pod, &container, swapLimit, config.Throttling)
+		}
+		lsm.managedContainers.add(containerUID, managedContainer{
+			namespace:      pod.Namespace,
+			pod:            pod.Name,
+			container:      container.Name,
+			cgroupPath:     dirPath,
+			swapLimitValue: applied,
+			softLimits:     throttled,
+		})
 	}
 
+	if failed {
+		return fmt.Errorf("some swap limits of pod %s could not be set", key), BackOff
+	} else if !found {
+		return nil, WaitForEvent
+	}
 	return nil, Forget
 }
 
-func calcSwapForBurstablePods(containerMemoryRequest, nodeTotalMemory, totalPodsSwapAvailable int64) int64 {
-	containerMemoryProportion := float64(containerMemoryRequest) / float64(nodeTotalMemory)
-	swapAllocation := containerMemoryProportion * float64(totalPodsSwapAvailable)
-
-	return int64(swapAllocation)
-}
-
-func setSwapLimit(dirPath string, swapLimit int64) error {
-	err := cgroups.WriteFile(dirPath, "memory.swap.max", strconv.FormatInt(swapLimit, 10))
-	return err
+// applySwapLimit sets the swap limit of a cgroup and returns the value of its swap limit file before and after.
+// In dry-run mode the cgroup is not changed, the swap limit it would be set to is only logged.
+func (lsm *LimitedSwapManager) applySwapLimit(cgroup, dirPath string, swapLimit int64) (current int64, applied int64, err error) {
+	if !lsm.dryRun {
+		return setSwapLimitForMode(lsm.cgroupMode, dirPath, swapLimit)
+	}
+	current, applied, err = readSwapLimitForMode(lsm.cgroupMode, dirPath, swapLimit)
+	if err == nil && current != applied {
+		log.Log.Infof("LimitedSwapManager: dry-run: would change %s of %s from %s to %s",
+			swapLimitFile(lsm.cgroupMode), cgroup, formatSwapLimitValue(current), formatSwapLimitValue(applied))
+	}
+	return current, applied, err
 }
 
-func getContainerStatusResponse(containerUID string) (*runtimeapi.ContainerStatusResponse, error) {
-	// Set up the gRPC connection to the CRI runtime
-	conn, err := grpc.Dial("unix:///var/run/crio/crio.sock", grpc.WithTransportCredentials(insecure.NewCredentials()))
-	if err != nil {
-		return nil, err
+// recordSwapExclusion logs the reason a pod gets no swap when it changes
+func (lsm *LimitedSwapManager) recordSwapExclusion(key string, pod *v1.Pod, reason string) {
+	metrics.SetPodSwapExclusion(pod.Namespace, pod.Name, reason)
+	previous, _ := lsm.exclusionReasons.Swap(key, reason)
+	if (previous == nil && reason == "") || previous == reason {
+		return
+	}
+	if reason == "" {
+		log.Log.Infof("LimitedSwapManager: pod %s is eligible for swap", key)
+	} else {
+		log.Log.Infof("LimitedSwapManager: pod %s gets no swap: %s", key, reason)
 	}
-	defer conn.Close()
-
-	// Create a RuntimeServiceClient
-	client := runtimeapi.NewRuntimeServiceClient(conn)
-
-	// Call the ContainerStatus API to get container information
-	request := &runtimeapi.ContainerStatusRequest{ContainerId: containerUID, Verbose: true}
-	response, err := client.ContainerStatus(context.Background(), request)
-
-	return response, err
 }
 
-type Data struct {
-	Pid int `json:"pid"`
+// recordAnnotationErrors reports the invalid swap annotations of a pod when they change,
+// so that a pod is not flooded with the same events on every reconciliation
+func (lsm *LimitedSwapManager) recordAnnotationErrors(key string, pod *v1.Pod, errs []error) {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	current := strings.Join(messages, "\n")
+	previous, _ := lsm.annotationErrors.Swap(key, current)
+	if (previous == nil && current == "") || previous == current {
+		return
+	}
+	for _, message := range messages {
+		log.Log.Infof("LimitedSwapManager: pod %v: %v", key, message)
+		lsm.recorder.Event(pod, v1.EventTypeWarning, InvalidSwapAnnotationReason, message)
+	}
 }
 
-func getContainerCgroupPath(containerUID string) (string, error) {
-	containerStatusResponse, err := getContainerStatusResponse(containerUID)
-	if err != nil {
-		return "", err
-	}
-	if containerStatusResponse.Info == nil {
-		return "", fmt.Errorf("Failed to get container status info")
+// containerSwapLimit computes the swap limit of a container, containers that are not eligible for swap get no swap
+func (lsm *LimitedSwapManager) containerSwapLimit(container *v1.Container, capacity NodeCapacity, swapConfig podSwapConfig, overrides swapOverrides, setAllContainersSwapToZero bool) int64 {
+	containerDoesNotRequestMemory := container.Resources.Requests.Memory().IsZero() && container.Resources.Limits.Memory().IsZero()
+	memoryRequestEqualsToLimit := container.Resources.Requests.Memory().Cmp(*container.Resources.Limits.Memory()) == 0
+	if containerDoesNotRequestMemory || memoryRequestEqualsToLimit || setAllContainersSwapToZero {
+		return 0
 	}
 
-	var data Data
-	err = json.Unmarshal([]byte(containerStatusResponse.Info["info"]), &data)
+	swapLimit := swapConfig.policy.SwapLimit(container, capacity)
+	swapLimit = swapConfig.capSwap(swapLimit)
+	return overrides.apply(swapLimit, capacity.Swap)
+}
+
+// setPodSwapLimit caps the swap of the pod cgroup to the swap of its containers, so the pod cannot
+// exceed its share when a container limit is mis-set, and the swap of the sandbox is accounted for.
+// It returns false when the swap limit could not be set.
+func (lsm *LimitedSwapManager) setPodSwapLimit(key string, pod *v1.Pod, capacity NodeCapacity, swapConfig podSwapConfig, overrides swapOverrides, setAllContainersSwapToZero bool) bool {
+	dirPath, err := lsm.cgroupPathResolver.podCgroupPath(pod)
 	if err != nil {
-		return "", err
-	}
-	if data.Pid == 0 {
-		return "", fmt.Errorf("PID not found in container info")
+		log.Log.Errorf("LimitedSwapManager: %v", err)
+		metrics.IncReconcileError(metrics.ReasonCRILookupFailed)
+		return false
 	}
 
-	return getCgroupPath(strconv.Itoa(data.Pid))
-}
+	swapLimit := podSwapLimit(pod, func(container *v1.Container) int64 {
+		return lsm.containerSwapLimit(container, capacity, swapConfig, overrides, setAllContainersSwapToZero)
+	})
+	swapLimit = overrides.applyToPod(swapLimit)
 
-// ReadCgroupFile reads the contents of the cgroup file at the given path.
-func getCgroupPath(pid string) (string, error) {
-	procCgroupBasePath := filepath.Join("/host/proc", pid, "cgroup")
-	controllerPaths, err := cgroups.ParseCgroupFile(procCgroupBasePath)
-	path, ok := controllerPaths[""]
+	_, _, err = lsm.applySwapLimit("pod "+key, dirPath, swapLimit)
 	if err != nil {
-		return "", err
-	} else if !ok {
-		return "", fmt.Errorf("could not get cgroup path")
+		log.Log.Infof("LimitSwapManager: couldn't set pod swap limit: %v", err.Error())
+		metrics.IncReconcileError(metrics.ReasonCgroupWriteFailed)
+		return false
 	}
-	return filepath.Join(cgroupPathBase, path), nil
+	metrics.SetPodSwapMax(pod.Namespace, pod.Name, swapLimit)
+	return true
 }
 
-func getContainerUID(pod *v1.Pod, container v1.Container) (string, error) {
-	prefix := "cri-o://"
+// getContainerID returns the container ID of the pod status, prefixed with the runtime scheme, e.g. cri-o://<id>
+func getContainerID(pod *v1.Pod, container v1.Container) (string, error) {
 	for _, conatinerStatus := range pod.Status.ContainerStatuses {
 		if conatinerStatus.Name == container.Name {
-			return strings.TrimPrefix(conatinerStatus.ContainerID, prefix), nil
+			return conatinerStatus.ContainerID, nil
 		}
 	}
 	for _, conatinerStatus := range pod.Status.InitContainerStatuses {
 		if conatinerStatus.Name == container.Name {
-			return strings.TrimPrefix(conatinerStatus.ContainerID, prefix), nil
+			return conatinerStatus.ContainerID, nil
 		}
 	}
 	return "", fmt.Errorf("cannot find ContainerUID PodName: %v containerName: %v", pod.Name, container.Name)
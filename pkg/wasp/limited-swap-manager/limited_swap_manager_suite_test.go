@@ -0,0 +1,20 @@
+package limited_swap_manager
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+)
+
+func TestLimitedSwapManager(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "LimitedSwapManager Suite")
+}
+
+var _ = BeforeSuite(func() {
+	// allow cgroup files to be faked in temporary directories
+	cgroups.TestMode = true
+})
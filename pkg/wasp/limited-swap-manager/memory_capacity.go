@@ -0,0 +1,81 @@
+package limited_swap_manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shirou/gopsutil/mem"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	// HostMemoryCapacity is the total memory of the host, including the memory reserved for the system
+	HostMemoryCapacity = "host"
+	// AllocatableMemoryCapacity is the allocatable memory of the Node, which pods can request
+	AllocatableMemoryCapacity = "allocatable"
+	// FixedMemoryCapacity is a memory size set in the agent configuration
+	FixedMemoryCapacity = "fixed"
+
+	DefaultMemoryCapacitySource = HostMemoryCapacity
+)
+
+// MemoryCapacitySource is where the node memory the swap of the containers is proportional to is read from
+type MemoryCapacitySource struct {
+	name  string
+	fixed int64
+}
+
+// NewMemoryCapacitySource returns the source registered under name, fixed is the memory of the fixed source
+func NewMemoryCapacitySource(name string, fixed resource.Quantity) (MemoryCapacitySource, error) {
+	switch name {
+	case "":
+		return MemoryCapacitySource{name: DefaultMemoryCapacitySource}, nil
+	case HostMemoryCapacity, AllocatableMemoryCapacity:
+		return MemoryCapacitySource{name: name}, nil
+	case FixedMemoryCapacity:
+		if fixed.Sign() <= 0 {
+			return MemoryCapacitySource{}, fmt.Errorf("the fixed memory capacity must be positive, got %q", fixed.String())
+		}
+		return MemoryCapacitySource{name: name, fixed: fixed.Value()}, nil
+	}
+	return MemoryCapacitySource{}, fmt.Errorf("unknown memory capacity source %q, supported sources: %v", name, MemoryCapacitySourceNames())
+}
+
+// MemoryCapacitySourceNames returns the names of all supported memory capacity sources
+func MemoryCapacitySourceNames() []string {
+	return []string{AllocatableMemoryCapacity, FixedMemoryCapacity, HostMemoryCapacity}
+}
+
+func (s MemoryCapacitySource) Name() string {
+	if s.name == "" {
+		return DefaultMemoryCapacitySource
+	}
+	return s.name
+}
+
+// read returns the memory of the node, nodes is only used by the allocatable source
+func (s MemoryCapacitySource) read(nodes corev1client.NodeInterface, nodeName string) (int64, error) {
+	switch s.Name() {
+	case FixedMemoryCapacity:
+		return s.fixed, nil
+	case AllocatableMemoryCapacity:
+		node, err := nodes.Get(context.TODO(), nodeName, metav1.GetOptions{})
+		if err != nil {
+			return 0, fmt.Errorf("error fetching node %s: %w", nodeName, err)
+		}
+		allocatable, ok := node.Status.Allocatable[v1.ResourceMemory]
+		if !ok || allocatable.Sign() <= 0 {
+			return 0, fmt.Errorf("node %s has no allocatable memory", nodeName)
+		}
+		return allocatable.Value(), nil
+	default:
+		virtualMem, err := mem.VirtualMemory()
+		if err != nil {
+			return 0, fmt.Errorf("error fetching virtual memory: %w", err)
+		}
+		return int64(virtualMem.Total), nil
+	}
+}
@@ -0,0 +1,72 @@
+package limited_swap_manager
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// fakeNodes returns a single node
+type fakeNodes struct {
+	corev1client.NodeInterface
+	node *v1.Node
+}
+
+func (f *fakeNodes) Get(_ context.Context, _ string, _ metav1.GetOptions) (*v1.Node, error) {
+	return f.node.DeepCopy(), nil
+}
+
+var _ = Describe("Memory capacity source", func() {
+	var nodes *fakeNodes
+
+	BeforeEach(func() {
+		nodes = &fakeNodes{node: &v1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node01"},
+			Status: v1.NodeStatus{
+				Capacity:    v1.ResourceList{v1.ResourceMemory: resource.MustParse("64Gi")},
+				Allocatable: v1.ResourceList{v1.ResourceMemory: resource.MustParse("60Gi")},
+			},
+		}}
+	})
+
+	It("should default to the host memory", func() {
+		source, err := NewMemoryCapacitySource("", resource.Quantity{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(source.Name()).To(Equal(HostMemoryCapacity))
+	})
+
+	DescribeTable("should reject invalid sources", func(name string, fixed resource.Quantity) {
+		_, err := NewMemoryCapacitySource(name, fixed)
+		Expect(err).To(HaveOccurred())
+	},
+		Entry("unknown source", "capacity", resource.Quantity{}),
+		Entry("fixed source without memory", FixedMemoryCapacity, resource.Quantity{}),
+		Entry("fixed source with negative memory", FixedMemoryCapacity, resource.MustParse("-1Gi")),
+	)
+
+	It("should read the allocatable memory of the node", func() {
+		source, err := NewMemoryCapacitySource(AllocatableMemoryCapacity, resource.Quantity{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(source.read(nodes, "node01")).To(Equal(int64(60 * 1024 * 1024 * 1024)))
+	})
+
+	It("should fail when the node reports no allocatable memory", func() {
+		nodes.node.Status.Allocatable = nil
+		source, err := NewMemoryCapacitySource(AllocatableMemoryCapacity, resource.Quantity{})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = source.read(nodes, "node01")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return the fixed memory", func() {
+		source, err := NewMemoryCapacitySource(FixedMemoryCapacity, resource.MustParse("48Gi"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(source.read(nodes, "node01")).To(Equal(int64(48 * 1024 * 1024 * 1024)))
+	})
+})
@@ -0,0 +1,87 @@
+package limited_swap_manager
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/openshift-virtualization/wasp-agent/pkg/log"
+	"github.com/openshift-virtualization/wasp-agent/pkg/monitoring/metrics"
+	"github.com/shirou/gopsutil/mem"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// nodeCapacity is the last memory and swap capacity read from the node,
+// it changes when swap is turned on or off or memory is hot-plugged
+type nodeCapacity struct {
+	lock     sync.RWMutex
+	capacity NodeCapacity
+	// known is false until the capacity was read once
+	known bool
+}
+
+// get returns the capacity of the node, and false when it was never read successfully
+func (c *nodeCapacity) get() (NodeCapacity, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.capacity, c.known
+}
+
+// set records the capacity of the node and returns whether it changed
+func (c *nodeCapacity) set(capacity NodeCapacity) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	changed := !c.known || c.capacity != capacity
+	c.capacity = capacity
+	c.known = true
+	return changed
+}
+
+// newNodeCapacityReader returns a function reading the swap of the node and its memory from source
+func newNodeCapacityReader(source MemoryCapacitySource, nodes corev1client.NodeInterface, nodeName string) func() (NodeCapacity, error) {
+	return func() (NodeCapacity, error) {
+		swap, err := mem.SwapMemory()
+		if err != nil {
+			return NodeCapacity{}, fmt.Errorf("error fetching swap memory: %w", err)
+		}
+		memory, err := source.read(nodes, nodeName)
+		if err != nil {
+			return NodeCapacity{}, err
+		}
+		return NodeCapacity{Memory: memory, Swap: int64(swap.Total)}, nil
+	}
+}
+
+// detectNodeCapacity reads the capacity of the node and reconciles all the pods when it changed,
+// since the swap limits computed by the policies depend on it. Read failures keep the last known capacity.
+// Pods are also reconciled when the capacity is first known, instead of waiting out the back off they got meanwhile.
+// The swap of the capacity is the swap the pods share, without the swap reserved for the system.
+func (lsm *LimitedSwapManager) detectNodeCapacity() {
+	capacity, err := lsm.readNodeCapacity()
+	if err != nil {
+		log.Log.Errorf("LimitedSwapManager: couldn't read the node capacity: %v", err)
+		return
+	}
+	capacity.Swap = lsm.kubepodsSwapLimit.podsSwap(capacity.Swap)
+	previous, known := lsm.nodeCapacity.get()
+	if !lsm.nodeCapacity.set(capacity) {
+		return
+	}
+	source := lsm.memoryCapacitySource.Name()
+	metrics.SetNodeCapacity(source, capacity.Memory, capacity.Swap)
+	if !known {
+		log.Log.Infof("LimitedSwapManager: node capacity is %d bytes of %s memory and %d bytes of swap for the pods", capacity.Memory, source, capacity.Swap)
+		lsm.enqueueAllPods()
+		return
+	}
+	log.Log.Infof("LimitedSwapManager: node capacity changed from %d to %d bytes of %s memory and from %d to %d bytes of swap for the pods, reconciling all pods",
+		previous.Memory, capacity.Memory, source, previous.Swap, capacity.Swap)
+	lsm.enqueueAllPods()
+}
+
+// reconcileNodeCapacity detects the changes of the capacity of the node and caps the swap of all the pods to it
+func (lsm *LimitedSwapManager) reconcileNodeCapacity() {
+	lsm.detectNodeCapacity()
+	if capacity, known := lsm.nodeCapacity.get(); known {
+		lsm.setKubepodsSwapLimit(capacity.Swap)
+	}
+}
@@ -0,0 +1,99 @@
+package limited_swap_manager
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var _ = Describe("Node capacity", func() {
+	const gi = 1024 * 1024 * 1024
+	var (
+		lsm      *LimitedSwapManager
+		capacity NodeCapacity
+		readErr  error
+	)
+
+	BeforeEach(func() {
+		podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		Expect(podIndexer.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns"}})).To(Succeed())
+		capacity = NodeCapacity{Memory: 64 * gi, Swap: 8 * gi}
+		readErr = nil
+		lsm = &LimitedSwapManager{
+			podLister: v1lister.NewPodLister(podIndexer),
+			podQueue:  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+			readNodeCapacity: func() (NodeCapacity, error) {
+				return capacity, readErr
+			},
+		}
+		DeferCleanup(lsm.podQueue.ShutDown)
+	})
+
+	drainQueue := func() {
+		for lsm.podQueue.Len() > 0 {
+			key, _ := lsm.podQueue.Get()
+			lsm.podQueue.Done(key)
+		}
+	}
+
+	It("should reconcile all the pods when the capacity is first read", func() {
+		lsm.detectNodeCapacity()
+		current, known := lsm.nodeCapacity.get()
+		Expect(known).To(BeTrue())
+		Expect(current).To(Equal(capacity))
+		Expect(lsm.SwapCapacity()).To(Equal(uint64(8 * gi)))
+		Expect(lsm.podQueue.Len()).To(Equal(1))
+	})
+
+	It("should reconcile all the pods when the capacity changes", func() {
+		lsm.detectNodeCapacity()
+		drainQueue()
+		lsm.detectNodeCapacity()
+		Expect(lsm.podQueue.Len()).To(BeZero())
+
+		capacity.Swap = 16 * gi
+		lsm.detectNodeCapacity()
+		Expect(lsm.SwapCapacity()).To(Equal(uint64(16 * gi)))
+		Expect(lsm.podQueue.Len()).To(Equal(1))
+	})
+
+	It("should compute the swap limits against the node swap without the system reserve", func() {
+		lsm.kubepodsSwapLimit = KubepodsSwapLimit{SystemReserve: 2 * gi}
+		lsm.detectNodeCapacity()
+		Expect(lsm.SwapCapacity()).To(Equal(uint64(6 * gi)))
+	})
+
+	It("should keep the last known capacity when it can not be read", func() {
+		lsm.detectNodeCapacity()
+		drainQueue()
+		readErr = fmt.Errorf("no /proc/meminfo")
+		capacity = NodeCapacity{}
+		lsm.detectNodeCapacity()
+		Expect(lsm.SwapCapacity()).To(Equal(uint64(8 * gi)))
+		Expect(lsm.podQueue.Len()).To(BeZero())
+	})
+
+	It("should not reconcile the pods until the capacity is known", func() {
+		readErr = fmt.Errorf("no /proc/meminfo")
+		lsm.detectNodeCapacity()
+		_, known := lsm.nodeCapacity.get()
+		Expect(known).To(BeFalse())
+		Expect(lsm.SwapCapacity()).To(BeZero())
+
+		err, state := lsm.execute("ns/pod")
+		Expect(err).To(HaveOccurred())
+		Expect(state).To(Equal(BackOff))
+		Expect(lsm.podQueue.Len()).To(BeZero())
+
+		readErr = nil
+		lsm.detectNodeCapacity()
+		Expect(lsm.podQueue.Len()).To(Equal(1))
+	})
+})
@@ -0,0 +1,52 @@
+package limited_swap_manager
+
+import (
+	"reflect"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// containerRunState is the part of a container status that requires the swap limit of the container to be set again
+type containerRunState struct {
+	containerID  string
+	restartCount int32
+	running      bool
+}
+
+// podNeedsReconcile returns whether a pod update may change the swap limits of the pod:
+// a container started or restarted, or the resources or the annotations of the pod changed.
+func podNeedsReconcile(oldPod, curPod *v1.Pod) bool {
+	if !reflect.DeepEqual(containerRunStates(oldPod), containerRunStates(curPod)) {
+		return true
+	}
+	if !reflect.DeepEqual(oldPod.Annotations, curPod.Annotations) || !reflect.DeepEqual(oldPod.Labels, curPod.Labels) {
+		return true
+	}
+	return resourcesChanged(oldPod.Spec.Containers, curPod.Spec.Containers) ||
+		resourcesChanged(oldPod.Spec.InitContainers, curPod.Spec.InitContainers)
+}
+
+// resourcesChanged returns whether the resources of the containers changed, e.g. when the pod is resized.
+// The resources of the init containers matter too, restartable init containers run next to the other containers.
+func resourcesChanged(oldContainers, curContainers []v1.Container) bool {
+	for i := range curContainers {
+		if i >= len(oldContainers) || !reflect.DeepEqual(oldContainers[i].Resources, curContainers[i].Resources) {
+			return true
+		}
+	}
+	return false
+}
+
+func containerRunStates(pod *v1.Pod) map[string]containerRunState {
+	states := map[string]containerRunState{}
+	for _, containerStatuses := range [][]v1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, containerStatus := range containerStatuses {
+			states[containerStatus.Name] = containerRunState{
+				containerID:  containerStatus.ContainerID,
+				restartCount: containerStatus.RestartCount,
+				running:      containerStatus.State.Running != nil,
+			}
+		}
+	}
+	return states
+}
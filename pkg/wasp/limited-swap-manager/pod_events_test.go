@@ -0,0 +1,76 @@
+package limited_swap_manager
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Pod events", func() {
+	var pod *v1.Pod
+
+	BeforeEach(func() {
+		pod = &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns", ResourceVersion: "1"},
+			Spec: v1.PodSpec{
+				Containers: []v1.Container{{
+					Name: "app",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+					},
+				}},
+				InitContainers: []v1.Container{{
+					Name: "sidecar",
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")},
+					},
+				}},
+			},
+			Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{
+				Name:        "app",
+				ContainerID: "cri-o://0123abcd",
+				State:       v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+			}}},
+		}
+	})
+
+	DescribeTable("should reconcile the pod", func(update func(pod *v1.Pod)) {
+		curPod := pod.DeepCopy()
+		update(curPod)
+		Expect(podNeedsReconcile(pod, curPod)).To(BeTrue())
+	},
+		Entry("when a container restarts", func(pod *v1.Pod) {
+			pod.Status.ContainerStatuses[0].RestartCount++
+			pod.Status.ContainerStatuses[0].ContainerID = "cri-o://4567ef01"
+		}),
+		Entry("when a container stops", func(pod *v1.Pod) {
+			pod.Status.ContainerStatuses[0].State = v1.ContainerState{Terminated: &v1.ContainerStateTerminated{}}
+		}),
+		Entry("when a container is added to the status", func(pod *v1.Pod) {
+			pod.Status.InitContainerStatuses = []v1.ContainerStatus{{Name: "init"}}
+		}),
+		Entry("when the annotations change", func(pod *v1.Pod) {
+			pod.Annotations = map[string]string{SwapDisabledAnnotation: "true"}
+		}),
+		Entry("when the labels change", func(pod *v1.Pod) {
+			pod.Labels = map[string]string{"app": "db"}
+		}),
+		Entry("when the resources are resized", func(pod *v1.Pod) {
+			pod.Spec.Containers[0].Resources.Requests[v1.ResourceMemory] = resource.MustParse("2Gi")
+		}),
+		Entry("when the resources of an init container are resized", func(pod *v1.Pod) {
+			pod.Spec.InitContainers[0].Resources.Requests[v1.ResourceMemory] = resource.MustParse("2Gi")
+		}),
+	)
+
+	It("should not reconcile the pod on other updates", func() {
+		curPod := pod.DeepCopy()
+		curPod.ResourceVersion = "2"
+		curPod.Status.Conditions = []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}}
+		curPod.Status.ContainerStatuses[0].Ready = true
+		Expect(podNeedsReconcile(pod, curPod)).To(BeFalse())
+	})
+})
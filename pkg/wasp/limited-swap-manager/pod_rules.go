@@ -0,0 +1,61 @@
+package limited_swap_manager
+
+import (
+	"path"
+	"slices"
+
+	"github.com/openshift-virtualization/wasp-agent/pkg/monitoring/metrics"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	kubeapiqos "k8s.io/kubernetes/pkg/apis/core/v1/helper/qos"
+	kubelettypes "k8s.io/kubernetes/pkg/kubelet/types"
+)
+
+// PodRules select pods by namespace, labels or priority class, a pod matches the rules when it matches any of them
+type PodRules struct {
+	// Namespaces are glob patterns of namespace names, e.g. "openshift-*"
+	Namespaces []string
+	// PodSelector selects pods by their labels, nil selects no pods
+	PodSelector labels.Selector
+	// PriorityClassNames are the priority classes of the selected pods
+	PriorityClassNames []string
+}
+
+func (r PodRules) isEmpty() bool {
+	return len(r.Namespaces) == 0 && r.PodSelector == nil && len(r.PriorityClassNames) == 0
+}
+
+// match returns the exclusion reason of the rule that matches the pod, or an empty string when no rule matches
+func (r PodRules) match(pod *v1.Pod) string {
+	for _, pattern := range r.Namespaces {
+		// patterns are validated when the configuration is loaded
+		if matched, _ := path.Match(pattern, pod.Namespace); matched {
+			return metrics.ExclusionNamespace
+		}
+	}
+	if r.PodSelector != nil && r.PodSelector.Matches(labels.Set(pod.Labels)) {
+		return metrics.ExclusionLabels
+	}
+	if pod.Spec.PriorityClassName != "" && slices.Contains(r.PriorityClassNames, pod.Spec.PriorityClassName) {
+		return metrics.ExclusionPriorityClass
+	}
+	return ""
+}
+
+// swapExclusionReason returns why the containers of the pod get no swap, or an empty string when they are eligible for it.
+// Exclusions take precedence over inclusions, when inclusions are set only the pods matching them get swap.
+func swapExclusionReason(pod *v1.Pod, swapConfig podSwapConfig, config Configuration) string {
+	if kubeapiqos.GetPodQOS(pod) != v1.PodQOSBurstable {
+		return metrics.ExclusionNotBurstable
+	}
+	if swapConfig.excludeCriticalPods && kubelettypes.IsCriticalPod(pod) {
+		return metrics.ExclusionCriticalPod
+	}
+	if reason := config.Exclusions.match(pod); reason != "" {
+		return reason
+	}
+	if !config.Inclusions.isEmpty() && config.Inclusions.match(pod) == "" {
+		return metrics.ExclusionNotIncluded
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package limited_swap_manager
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/openshift-virtualization/wasp-agent/pkg/monitoring/metrics"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	schedulingapi "k8s.io/kubernetes/pkg/apis/scheduling"
+)
+
+var _ = Describe("Pod rules", func() {
+	swapConfig := podSwapConfig{excludeCriticalPods: true}
+
+	newPod := func(namespace string, podLabels map[string]string, priorityClassName string) *v1.Pod {
+		return &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "test", Namespace: namespace, Labels: podLabels},
+			Spec: v1.PodSpec{
+				PriorityClassName: priorityClassName,
+				Containers:        []v1.Container{*burstableContainer("1Gi", "2Gi")},
+			},
+		}
+	}
+
+	criticalPod := func() *v1.Pod {
+		pod := newPod("kube-system", nil, "system-node-critical")
+		priority := int32(schedulingapi.SystemCriticalPriority)
+		pod.Spec.Priority = &priority
+		return pod
+	}
+
+	DescribeTable("should return the reason a pod gets no swap", func(pod *v1.Pod, config Configuration, expected string) {
+		Expect(swapExclusionReason(pod, swapConfig, config)).To(Equal(expected))
+	},
+		Entry("eligible pod", newPod("default", nil, ""), Configuration{}, ""),
+		Entry("best effort pod", &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "test"}}}},
+			Configuration{}, metrics.ExclusionNotBurstable),
+		Entry("critical pod", criticalPod(), Configuration{}, metrics.ExclusionCriticalPod),
+		Entry("namespace matching a glob", newPod("openshift-monitoring", nil, ""),
+			Configuration{Exclusions: PodRules{Namespaces: []string{"openshift-*"}}}, metrics.ExclusionNamespace),
+		Entry("namespace not matching a glob", newPod("openshift", nil, ""),
+			Configuration{Exclusions: PodRules{Namespaces: []string{"openshift-*"}}}, ""),
+		Entry("labels matching the selector", newPod("default", map[string]string{"app": "db"}, ""),
+			Configuration{Exclusions: PodRules{PodSelector: labels.SelectorFromSet(labels.Set{"app": "db"})}}, metrics.ExclusionLabels),
+		Entry("excluded priority class", newPod("default", nil, "latency-sensitive"),
+			Configuration{Exclusions: PodRules{PriorityClassNames: []string{"latency-sensitive"}}}, metrics.ExclusionPriorityClass),
+		Entry("pod not matching the inclusions", newPod("default", nil, ""),
+			Configuration{Inclusions: PodRules{Namespaces: []string{"vms-*"}}}, metrics.ExclusionNotIncluded),
+		Entry("pod matching any of the inclusions", newPod("default", map[string]string{"kubevirt.io": "virt-launcher"}, ""),
+			Configuration{Inclusions: PodRules{
+				Namespaces:  []string{"vms-*"},
+				PodSelector: labels.SelectorFromSet(labels.Set{"kubevirt.io": "virt-launcher"}),
+			}}, ""),
+		Entry("pod matching both inclusions and exclusions", newPod("vms-dev", nil, ""),
+			Configuration{
+				Inclusions: PodRules{Namespaces: []string{"vms-*"}},
+				Exclusions: PodRules{Namespaces: []string{"vms-dev"}},
+			}, metrics.ExclusionNamespace),
+	)
+
+	It("should not exclude critical pods when the swap policy allows them", func() {
+		Expect(swapExclusionReason(criticalPod(), podSwapConfig{}, Configuration{})).To(BeEmpty())
+	})
+})
@@ -0,0 +1,41 @@
+package limited_swap_manager
+
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
+// podSwapLimit returns the swap the containers of the pod can use at the same time.
+// Regular and sidecar containers run together, while the other init containers
+// run one at a time next to the sidecars started before them.
+func podSwapLimit(pod *v1.Pod, containerSwapLimit func(container *v1.Container) int64) int64 {
+	sidecarsSwapLimit := int64(0)
+	initSwapLimit := int64(0)
+	for i := range pod.Spec.InitContainers {
+		container := &pod.Spec.InitContainers[i]
+		if container.RestartPolicy != nil && *container.RestartPolicy == v1.ContainerRestartPolicyAlways {
+			sidecarsSwapLimit = addSwapLimits(sidecarsSwapLimit, containerSwapLimit(container))
+			continue
+		}
+		initSwapLimit = maxSwapLimit(initSwapLimit, addSwapLimits(sidecarsSwapLimit, containerSwapLimit(container)))
+	}
+
+	swapLimit := sidecarsSwapLimit
+	for i := range pod.Spec.Containers {
+		swapLimit = addSwapLimits(swapLimit, containerSwapLimit(&pod.Spec.Containers[i]))
+	}
+	return maxSwapLimit(swapLimit, initSwapLimit)
+}
+
+func addSwapLimits(a, b int64) int64 {
+	if a == UnlimitedSwap || b == UnlimitedSwap {
+		return UnlimitedSwap
+	}
+	return a + b
+}
+
+func maxSwapLimit(a, b int64) int64 {
+	if a == UnlimitedSwap || b == UnlimitedSwap {
+		return UnlimitedSwap
+	}
+	return max(a, b)
+}
@@ -0,0 +1,64 @@
+package limited_swap_manager
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+var _ = Describe("Pod swap limit", func() {
+	var swapLimits map[string]int64
+
+	containerSwapLimit := func(container *v1.Container) int64 {
+		return swapLimits[container.Name]
+	}
+
+	sidecar := func(name string) v1.Container {
+		restartPolicy := v1.ContainerRestartPolicyAlways
+		return v1.Container{Name: name, RestartPolicy: &restartPolicy}
+	}
+
+	BeforeEach(func() {
+		swapLimits = map[string]int64{"app": 1000, "helper": 500, "init": 2000, "sidecar": 300}
+	})
+
+	DescribeTable("should sum the swap of the containers running together", func(pod *v1.Pod, expected int64) {
+		Expect(podSwapLimit(pod, containerSwapLimit)).To(Equal(expected))
+	},
+		Entry("with regular containers", &v1.Pod{Spec: v1.PodSpec{
+			Containers: []v1.Container{{Name: "app"}, {Name: "helper"}},
+		}}, int64(1500)),
+		Entry("with an init container larger than the regular containers", &v1.Pod{Spec: v1.PodSpec{
+			InitContainers: []v1.Container{{Name: "init"}},
+			Containers:     []v1.Container{{Name: "app"}, {Name: "helper"}},
+		}}, int64(2000)),
+		Entry("with a sidecar running next to the init and the regular containers", &v1.Pod{Spec: v1.PodSpec{
+			InitContainers: []v1.Container{sidecar("sidecar"), {Name: "init"}},
+			Containers:     []v1.Container{{Name: "app"}},
+		}}, int64(2300)),
+		Entry("with a sidecar started after the init container", &v1.Pod{Spec: v1.PodSpec{
+			InitContainers: []v1.Container{{Name: "init"}, sidecar("sidecar")},
+			Containers:     []v1.Container{{Name: "app"}},
+		}}, int64(2000)),
+		Entry("without containers", &v1.Pod{}, int64(0)),
+	)
+
+	It("should not cap the pod when a container has unlimited swap", func() {
+		swapLimits["helper"] = UnlimitedSwap
+		pod := &v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}, {Name: "helper"}}}}
+		Expect(podSwapLimit(pod, containerSwapLimit)).To(Equal(UnlimitedSwap))
+	})
+
+	DescribeTable("should apply the pod annotations", func(annotations map[string]string, podSwapLimit, expected int64) {
+		overrides, errs := parseSwapOverrides(podWithAnnotations(annotations))
+		Expect(errs).To(BeEmpty())
+		Expect(overrides.applyToPod(podSwapLimit)).To(Equal(expected))
+	},
+		Entry("without annotations", nil, int64(1500), int64(1500)),
+		Entry("when the pod limit is lower", map[string]string{PodSwapLimitAnnotation: "1000"}, int64(1500), int64(1000)),
+		Entry("when the pod limit is higher", map[string]string{PodSwapLimitAnnotation: "2000"}, int64(1500), int64(1500)),
+		Entry("when the containers have unlimited swap", map[string]string{PodSwapLimitAnnotation: "1Gi"}, UnlimitedSwap, int64(1024*1024*1024)),
+		Entry("when swap is disabled", map[string]string{SwapDisabledAnnotation: "true"}, int64(1500), int64(0)),
+	)
+})
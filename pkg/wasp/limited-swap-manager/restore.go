@@ -0,0 +1,89 @@
+package limited_swap_manager
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/openshift-virtualization/wasp-agent/pkg/log"
+	"github.com/openshift-virtualization/wasp-agent/pkg/monitoring/metrics"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// RestoreSwapLimits reverts the swap limits set by the agent on all the pods of the node, it is used when the agent is uninstalled.
+// The swap limits found before the agent was installed are not known, so the containers are reverted to no swap
+// and the pod and QoS cgroups to no swap limit, as the kubelet sets them when swap is not enabled.
+// The soft limits of the containers managed by the configured throttling are reverted to max.
+// It returns the number of containers whose swap limit was reverted.
+func (lsm *LimitedSwapManager) RestoreSwapLimits() (int, error) {
+	pods, err := lsm.podLister.List(labels.Everything())
+	if err != nil {
+		return 0, err
+	}
+
+	restored := 0
+	var errs []error
+	for _, pod := range pods {
+		podRestored, podErrs := lsm.restorePodSwapLimits(pod)
+		restored += podRestored
+		errs = append(errs, podErrs...)
+	}
+	errs = append(errs, lsm.restoreKubepodsSwapLimit()...)
+	log.Log.Infof("LimitedSwapManager: reverted the swap limit of %d containers, %d errors", restored, len(errs))
+	return restored, errors.Join(errs...)
+}
+
+func (lsm *LimitedSwapManager) restorePodSwapLimits(pod *v1.Pod) (int, []error) {
+	key := pod.Namespace + "/" + pod.Name
+	restored := 0
+	var errs []error
+	for _, container := range append(pod.Spec.Containers, pod.Spec.InitContainers...) {
+		containerState, exist := getContainerState(pod, container)
+		if !exist || containerState.Running == nil {
+			continue
+		}
+		containerID, err := getContainerID(pod, container)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		runtime, containerUID, err := lsm.containerRuntimes.ForContainerID(containerID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		dirPath, err := lsm.cgroupPathResolver.containerCgroupPath(pod, runtime, containerUID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		current, applied, err := lsm.applySwapLimit("container "+key+"/"+container.Name, dirPath, 0)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to revert the swap limit of container %s/%s: %w", key, container.Name, err))
+			continue
+		}
+		if current != applied {
+			restored++
+			metrics.IncSwapLimitRestored()
+		}
+		if lsm.cgroupMode == cgroupV2 {
+			if err := lsm.restoreThrottling("container "+key+"/"+container.Name, dirPath, lsm.configuration().Throttling); err != nil {
+				errs = append(errs, fmt.Errorf("failed to revert the soft limits of container %s/%s: %w", key, container.Name, err))
+			}
+		}
+	}
+
+	if _, ok := pod.Annotations[PodSwapLimitAnnotation]; ok || lsm.configuration().PodSwapLimit {
+		dirPath, err := lsm.cgroupPathResolver.podCgroupPath(pod)
+		if err == nil {
+			_, _, err = lsm.applySwapLimit("pod "+key, dirPath, UnlimitedSwap)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to revert the swap limit of pod %s: %w", key, err))
+		}
+	}
+
+	metrics.DeletePodMetrics(pod.Namespace, pod.Name)
+	lsm.managedContainers.removePod(pod.Namespace, pod.Name)
+	return restored, errs
+}
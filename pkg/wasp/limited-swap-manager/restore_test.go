@@ -0,0 +1,81 @@
+package limited_swap_manager
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	container_runtime "github.com/openshift-virtualization/wasp-agent/pkg/wasp/container-runtime"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+var _ = Describe("Swap limit restoration", func() {
+	const containerCgroup = "kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod6d4e3c1a_8f2b_4a5c_9d7e_0b1c2d3e4f5a.slice/crio-0123abcd.scope"
+
+	var (
+		lsm        *LimitedSwapManager
+		podIndexer cache.Indexer
+		cgroupDir  string
+	)
+
+	readSwapMax := func() string {
+		swapMax, err := os.ReadFile(filepath.Join(cgroupDir, swapMaxFile))
+		Expect(err).ToNot(HaveOccurred())
+		return string(swapMax)
+	}
+
+	BeforeEach(func() {
+		podIndexer = cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		resolver := &cgroupPathResolver{cgroupRoot: GinkgoT().TempDir(), procRoot: GinkgoT().TempDir()}
+		lsm = &LimitedSwapManager{
+			podLister:          v1lister.NewPodLister(podIndexer),
+			managedContainers:  newManagedContainers(),
+			containerRuntimes:  container_runtime.NewContainerRuntimes(container_runtime.NewCrio(container_runtime.CrioSocketPath, container_runtime.DefaultCallTimeout)),
+			cgroupPathResolver: resolver,
+			cgroupMode:         cgroupV2,
+		}
+
+		cgroupDir = filepath.Join(resolver.cgroupRoot, containerCgroup)
+		Expect(os.MkdirAll(cgroupDir, 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(cgroupDir, swapMaxFile), []byte("1073741824\n"), 0644)).To(Succeed())
+
+		Expect(podIndexer.Add(&v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "ns", UID: types.UID(testPodUID)},
+			Spec: v1.PodSpec{Containers: []v1.Container{{
+				Name:      "container",
+				Resources: v1.ResourceRequirements{Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")}},
+			}}},
+			Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{
+				Name:        "container",
+				ContainerID: "cri-o://" + testContainerID,
+				State:       v1.ContainerState{Running: &v1.ContainerStateRunning{}},
+			}}},
+		})).To(Succeed())
+	})
+
+	It("should revert the containers to no swap", func() {
+		restored, err := lsm.RestoreSwapLimits()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(restored).To(Equal(1))
+		Expect(readSwapMax()).To(Equal("0"))
+
+		restored, err = lsm.RestoreSwapLimits()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(restored).To(BeZero())
+	})
+
+	It("should only report the containers it would revert in dry-run mode", func() {
+		lsm.dryRun = true
+		restored, err := lsm.RestoreSwapLimits()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(restored).To(Equal(1))
+		Expect(readSwapMax()).To(Equal("1073741824\n"))
+	})
+})
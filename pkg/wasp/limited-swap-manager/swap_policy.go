@@ -0,0 +1,154 @@
+package limited_swap_manager
+
+import (
+	"fmt"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// ProportionalToRequestPolicy grants swap proportionally to the container memory request,
+	// same as `swapBehavior: LimitedSwap` in kubelet. This is the default policy.
+	ProportionalToRequestPolicy = "proportional-to-request"
+	// ProportionalToLimitMinusRequestPolicy grants swap proportionally to the container burst
+	// range, i.e. the difference between its memory limit and its memory request.
+	ProportionalToLimitMinusRequestPolicy = "proportional-to-limit-minus-request"
+	// FixedCapPolicy grants every burstable container the same fixed amount of swap.
+	FixedCapPolicy = "fixed-cap"
+	// UnlimitedForBurstablePolicy does not cap swap for burstable containers.
+	UnlimitedForBurstablePolicy = "unlimited-for-burstable"
+
+	DefaultSwapPolicy = ProportionalToRequestPolicy
+
+	// UnlimitedSwap is returned by a SwapPolicy when swap should not be capped
+	UnlimitedSwap int64 = -1
+)
+
+// NodeCapacity holds the node resources a SwapPolicy computes swap limits against
+type NodeCapacity struct {
+	Memory int64
+	Swap   int64
+}
+
+// SwapPolicy calculates the swap limit of a burstable container.
+// Containers that are not eligible for swap never reach the policy, their limit is always zero.
+type SwapPolicy interface {
+	Name() string
+	// SwapLimit returns the value for memory.swap.max in bytes, or UnlimitedSwap
+	SwapLimit(container *v1.Container, capacity NodeCapacity) int64
+}
+
+// SwapPolicyOptions holds the parameters of the configurable policies
+type SwapPolicyOptions struct {
+	// FixedCap is the swap granted to every burstable container by the fixed-cap policy
+	FixedCap resource.Quantity
+}
+
+var swapPolicyFactories = map[string]func(SwapPolicyOptions) (SwapPolicy, error){
+	ProportionalToRequestPolicy: func(SwapPolicyOptions) (SwapPolicy, error) {
+		return &proportionalToRequest{}, nil
+	},
+	ProportionalToLimitMinusRequestPolicy: func(SwapPolicyOptions) (SwapPolicy, error) {
+		return &proportionalToLimitMinusRequest{}, nil
+	},
+	FixedCapPolicy: newFixedCap,
+	UnlimitedForBurstablePolicy: func(SwapPolicyOptions) (SwapPolicy, error) {
+		return &unlimitedForBurstable{}, nil
+	},
+}
+
+// NewSwapPolicy returns the SwapPolicy registered under name
+func NewSwapPolicy(name string, opts SwapPolicyOptions) (SwapPolicy, error) {
+	if name == "" {
+		name = DefaultSwapPolicy
+	}
+	factory, ok := swapPolicyFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown swap policy %q, supported policies: %v", name, SwapPolicyNames())
+	}
+	return factory(opts)
+}
+
+// SwapPolicyNames returns the names of all supported swap policies
+func SwapPolicyNames() []string {
+	names := make([]string, 0, len(swapPolicyFactories))
+	for name := range swapPolicyFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type proportionalToRequest struct{}
+
+func (p *proportionalToRequest) Name() string {
+	return ProportionalToRequestPolicy
+}
+
+func (p *proportionalToRequest) SwapLimit(container *v1.Container, capacity NodeCapacity) int64 {
+	containerMemoryRequest := container.Resources.Requests.Memory()
+	return calcSwapForBurstablePods(containerMemoryRequest.Value(), capacity.Memory, capacity.Swap)
+}
+
+type proportionalToLimitMinusRequest struct{}
+
+func (p *proportionalToLimitMinusRequest) Name() string {
+	return ProportionalToLimitMinusRequestPolicy
+}
+
+// SwapLimit grants no swap to containers without a memory limit, since their burst range is unbounded
+func (p *proportionalToLimitMinusRequest) SwapLimit(container *v1.Container, capacity NodeCapacity) int64 {
+	limit := container.Resources.Limits.Memory()
+	if limit.IsZero() {
+		return 0
+	}
+	burst := limit.Value() - container.Resources.Requests.Memory().Value()
+	if burst <= 0 {
+		return 0
+	}
+	return calcSwapForBurstablePods(burst, capacity.Memory, capacity.Swap)
+}
+
+type fixedCap struct {
+	capBytes int64
+}
+
+func newFixedCap(opts SwapPolicyOptions) (SwapPolicy, error) {
+	if opts.FixedCap.Sign() <= 0 {
+		return nil, fmt.Errorf("swap policy %s requires a positive fixed cap, got %q", FixedCapPolicy, opts.FixedCap.String())
+	}
+	return &fixedCap{capBytes: opts.FixedCap.Value()}, nil
+}
+
+func (p *fixedCap) Name() string {
+	return FixedCapPolicy
+}
+
+func (p *fixedCap) SwapLimit(_ *v1.Container, capacity NodeCapacity) int64 {
+	if p.capBytes > capacity.Swap {
+		return capacity.Swap
+	}
+	return p.capBytes
+}
+
+type unlimitedForBurstable struct{}
+
+func (p *unlimitedForBurstable) Name() string {
+	return UnlimitedForBurstablePolicy
+}
+
+func (p *unlimitedForBurstable) SwapLimit(_ *v1.Container, _ NodeCapacity) int64 {
+	return UnlimitedSwap
+}
+
+func calcSwapForBurstablePods(containerMemoryRequest, nodeTotalMemory, totalPodsSwapAvailable int64) int64 {
+	if nodeTotalMemory <= 0 {
+		return 0
+	}
+	containerMemoryProportion := float64(containerMemoryRequest) / float64(nodeTotalMemory)
+	swapAllocation := containerMemoryProportion * float64(totalPodsSwapAvailable)
+
+	return int64(swapAllocation)
+}
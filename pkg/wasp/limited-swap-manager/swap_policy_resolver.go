@@ -0,0 +1,179 @@
+package limited_swap_manager
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	waspv1alpha1 "github.com/openshift-virtualization/wasp-agent/pkg/apis/core/v1alpha1"
+	"github.com/openshift-virtualization/wasp-agent/pkg/log"
+	v1 "k8s.io/api/core/v1"
+	kapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+const defaultSwapPolicySource = "default"
+
+// podSwapConfig is the swap configuration that applies to a pod
+type podSwapConfig struct {
+	policy              SwapPolicy
+	maxSwap             *int64
+	excludeCriticalPods bool
+	// source is the name of the SwapPolicy object the configuration comes from
+	source string
+	// strategySet is true when the policy is the strategy of the SwapPolicy object
+	strategySet bool
+}
+
+// capSwap applies the MaxSwap of the SwapPolicy object to the swap limit computed by the policy
+func (c podSwapConfig) capSwap(swapLimit int64) int64 {
+	if c.maxSwap != nil && (swapLimit == UnlimitedSwap || swapLimit > *c.maxSwap) {
+		return *c.maxSwap
+	}
+	return swapLimit
+}
+
+// swapPolicyResolver finds the SwapPolicy object selecting a pod.
+// Pods that are not selected by any object, or all pods when the SwapPolicy CRD is not installed,
+// get the policy configured on the agent.
+type swapPolicyResolver struct {
+	swapPolicyStore cache.Store
+	namespaceLister v1lister.NamespaceLister
+	lock            sync.RWMutex
+	defaultConfig   podSwapConfig
+}
+
+func newSwapPolicyResolver(swapPolicyInformer, namespaceInformer cache.SharedIndexInformer, defaultPolicy SwapPolicy) *swapPolicyResolver {
+	resolver := &swapPolicyResolver{
+		defaultConfig: podSwapConfig{
+			policy:              defaultPolicy,
+			excludeCriticalPods: true,
+			source:              defaultSwapPolicySource,
+		},
+	}
+	if swapPolicyInformer != nil && namespaceInformer != nil {
+		resolver.swapPolicyStore = swapPolicyInformer.GetStore()
+		resolver.namespaceLister = v1lister.NewNamespaceLister(namespaceInformer.GetIndexer())
+	}
+	return resolver
+}
+
+// setDefaultPolicy changes the policy configured on the agent
+func (r *swapPolicyResolver) setDefaultPolicy(policy SwapPolicy) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.defaultConfig.policy = policy
+}
+
+func (r *swapPolicyResolver) defaults() podSwapConfig {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.defaultConfig
+}
+
+func (r *swapPolicyResolver) resolve(pod *v1.Pod) (podSwapConfig, error) {
+	if r.swapPolicyStore == nil {
+		return r.defaults(), nil
+	}
+
+	namespace, err := r.namespaceLister.Get(pod.Namespace)
+	if kapierrors.IsNotFound(err) {
+		return r.defaults(), nil
+	} else if err != nil {
+		return podSwapConfig{}, err
+	}
+
+	for _, swapPolicy := range r.sortedSwapPolicies() {
+		matches, err := swapPolicyMatches(swapPolicy, namespace, pod)
+		if err != nil {
+			log.Log.Infof("LimitedSwapManager: skipping SwapPolicy %s: %v", swapPolicy.Name, err)
+			continue
+		}
+		if !matches {
+			continue
+		}
+
+		config, err := r.podSwapConfigFor(swapPolicy)
+		if err != nil {
+			log.Log.Infof("LimitedSwapManager: skipping SwapPolicy %s: %v", swapPolicy.Name, err)
+			continue
+		}
+		return config, nil
+	}
+
+	return r.defaults(), nil
+}
+
+// sortedSwapPolicies returns the SwapPolicy objects by descending priority, ties broken by name
+func (r *swapPolicyResolver) sortedSwapPolicies() []*waspv1alpha1.SwapPolicy {
+	var swapPolicies []*waspv1alpha1.SwapPolicy
+	for _, obj := range r.swapPolicyStore.List() {
+		if swapPolicy, ok := obj.(*waspv1alpha1.SwapPolicy); ok {
+			swapPolicies = append(swapPolicies, swapPolicy)
+		}
+	}
+	sort.Slice(swapPolicies, func(i, j int) bool {
+		if swapPolicies[i].Spec.Priority != swapPolicies[j].Spec.Priority {
+			return swapPolicies[i].Spec.Priority > swapPolicies[j].Spec.Priority
+		}
+		return swapPolicies[i].Name < swapPolicies[j].Name
+	})
+	return swapPolicies
+}
+
+func (r *swapPolicyResolver) podSwapConfigFor(swapPolicy *waspv1alpha1.SwapPolicy) (podSwapConfig, error) {
+	config := podSwapConfig{
+		policy:              r.defaults().policy,
+		excludeCriticalPods: true,
+		source:              swapPolicy.Name,
+	}
+
+	if swapPolicy.Spec.Strategy != "" {
+		opts := SwapPolicyOptions{}
+		if swapPolicy.Spec.FixedCap != nil {
+			opts.FixedCap = *swapPolicy.Spec.FixedCap
+		}
+		policy, err := NewSwapPolicy(swapPolicy.Spec.Strategy, opts)
+		if err != nil {
+			return podSwapConfig{}, err
+		}
+		config.policy = policy
+		config.strategySet = true
+	}
+
+	if swapPolicy.Spec.MaxSwap != nil {
+		if swapPolicy.Spec.MaxSwap.Sign() < 0 {
+			return podSwapConfig{}, fmt.Errorf("maxSwap must not be negative, got %q", swapPolicy.Spec.MaxSwap.String())
+		}
+		maxSwap := swapPolicy.Spec.MaxSwap.Value()
+		config.maxSwap = &maxSwap
+	}
+
+	if swapPolicy.Spec.ExcludeCriticalPods != nil {
+		config.excludeCriticalPods = *swapPolicy.Spec.ExcludeCriticalPods
+	}
+
+	return config, nil
+}
+
+func swapPolicyMatches(swapPolicy *waspv1alpha1.SwapPolicy, namespace *v1.Namespace, pod *v1.Pod) (bool, error) {
+	matches, err := selectorMatches(swapPolicy.Spec.NamespaceSelector, namespace.Labels)
+	if err != nil || !matches {
+		return false, err
+	}
+	return selectorMatches(swapPolicy.Spec.PodSelector, pod.Labels)
+}
+
+func selectorMatches(labelSelector *metav1.LabelSelector, objLabels map[string]string) (bool, error) {
+	if labelSelector == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(objLabels)), nil
+}
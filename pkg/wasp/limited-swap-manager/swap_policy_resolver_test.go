@@ -0,0 +1,167 @@
+package limited_swap_manager
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	waspv1alpha1 "github.com/openshift-virtualization/wasp-agent/pkg/apis/core/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/pointer"
+)
+
+var _ = Describe("SwapPolicy resolution", func() {
+	var (
+		resolver        *swapPolicyResolver
+		swapPolicyStore cache.Store
+		defaultPolicy   SwapPolicy
+		pod             *v1.Pod
+	)
+
+	BeforeEach(func() {
+		var err error
+		defaultPolicy, err = NewSwapPolicy(DefaultSwapPolicy, SwapPolicyOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		namespaceIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		Expect(namespaceIndexer.Add(&v1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"swap": "enabled"}},
+		})).To(Succeed())
+
+		swapPolicyStore = cache.NewStore(cache.MetaNamespaceKeyFunc)
+		resolver = &swapPolicyResolver{
+			swapPolicyStore: swapPolicyStore,
+			namespaceLister: v1lister.NewNamespaceLister(namespaceIndexer),
+			defaultConfig: podSwapConfig{
+				policy:              defaultPolicy,
+				excludeCriticalPods: true,
+				source:              defaultSwapPolicySource,
+			},
+		}
+
+		pod = &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "team-a", Labels: map[string]string{"app": "db"}},
+		}
+	})
+
+	addSwapPolicy := func(name string, spec waspv1alpha1.SwapPolicySpec) {
+		Expect(swapPolicyStore.Add(&waspv1alpha1.SwapPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       spec,
+		})).To(Succeed())
+	}
+
+	It("should use the agent policy when no SwapPolicy selects the pod", func() {
+		config, err := resolver.resolve(pod)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.source).To(Equal(defaultSwapPolicySource))
+		Expect(config.policy.Name()).To(Equal(DefaultSwapPolicy))
+		Expect(config.excludeCriticalPods).To(BeTrue())
+	})
+
+	It("should use the agent policy when the CRD is not installed", func() {
+		resolver = newSwapPolicyResolver(nil, nil, defaultPolicy)
+		config, err := resolver.resolve(pod)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.source).To(Equal(defaultSwapPolicySource))
+	})
+
+	It("should select pods by namespace and pod labels", func() {
+		fixedCap := resource.MustParse("1Gi")
+		addSwapPolicy("other-namespaces", waspv1alpha1.SwapPolicySpec{
+			NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"swap": "disabled"}},
+		})
+		addSwapPolicy("other-pods", waspv1alpha1.SwapPolicySpec{
+			PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		})
+		addSwapPolicy("db", waspv1alpha1.SwapPolicySpec{
+			NamespaceSelector:   &metav1.LabelSelector{MatchLabels: map[string]string{"swap": "enabled"}},
+			PodSelector:         &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}},
+			Strategy:            FixedCapPolicy,
+			FixedCap:            &fixedCap,
+			ExcludeCriticalPods: pointer.Bool(false),
+		})
+
+		config, err := resolver.resolve(pod)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.source).To(Equal("db"))
+		Expect(config.policy.Name()).To(Equal(FixedCapPolicy))
+		Expect(config.excludeCriticalPods).To(BeFalse())
+	})
+
+	It("should prefer the highest priority, then the name", func() {
+		addSwapPolicy("b-low", waspv1alpha1.SwapPolicySpec{Priority: 1})
+		addSwapPolicy("c-high", waspv1alpha1.SwapPolicySpec{Priority: 10})
+		addSwapPolicy("a-high", waspv1alpha1.SwapPolicySpec{Priority: 10})
+
+		config, err := resolver.resolve(pod)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.source).To(Equal("a-high"))
+	})
+
+	It("should skip invalid SwapPolicies", func() {
+		addSwapPolicy("a-invalid", waspv1alpha1.SwapPolicySpec{Strategy: "no-such-strategy"})
+		addSwapPolicy("b-valid", waspv1alpha1.SwapPolicySpec{Strategy: UnlimitedForBurstablePolicy})
+
+		config, err := resolver.resolve(pod)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.source).To(Equal("b-valid"))
+	})
+
+	It("should cap swap with maxSwap", func() {
+		maxSwap := resource.MustParse("512Mi")
+		addSwapPolicy("capped", waspv1alpha1.SwapPolicySpec{Strategy: UnlimitedForBurstablePolicy, MaxSwap: &maxSwap})
+
+		config, err := resolver.resolve(pod)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.capSwap(UnlimitedSwap)).To(Equal(maxSwap.Value()))
+		Expect(config.capSwap(1024)).To(Equal(int64(1024)))
+	})
+})
+
+var _ = Describe("Namespace label changes", func() {
+	var lsm *LimitedSwapManager
+
+	BeforeEach(func() {
+		podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		for _, key := range []struct{ namespace, name string }{{"team-a", "a1"}, {"team-a", "a2"}, {"team-b", "b1"}} {
+			Expect(podIndexer.Add(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: key.name, Namespace: key.namespace}})).To(Succeed())
+		}
+		lsm = &LimitedSwapManager{
+			podLister: v1lister.NewPodLister(podIndexer),
+			podQueue:  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		}
+		DeferCleanup(lsm.podQueue.ShutDown)
+	})
+
+	queuedKeys := func() []string {
+		var keys []string
+		for lsm.podQueue.Len() > 0 {
+			key, _ := lsm.podQueue.Get()
+			keys = append(keys, key.(string))
+			lsm.podQueue.Done(key)
+		}
+		return keys
+	}
+
+	It("should reconcile the pods of a namespace when its labels change", func() {
+		old := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"swap": "enabled"}}}
+		curr := old.DeepCopy()
+		curr.Labels["swap"] = "disabled"
+		lsm.namespaceUpdated(old, curr)
+		Expect(queuedKeys()).To(ConsistOf("team-a/a1", "team-a/a2"))
+	})
+
+	It("should not reconcile the pods of a namespace on other updates", func() {
+		old := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"swap": "enabled"}, ResourceVersion: "1"}}
+		curr := old.DeepCopy()
+		curr.ResourceVersion = "2"
+		curr.Annotations = map[string]string{"owner": "team-a"}
+		lsm.namespaceUpdated(old, curr)
+		Expect(queuedKeys()).To(BeEmpty())
+	})
+})
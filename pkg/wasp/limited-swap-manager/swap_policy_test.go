@@ -0,0 +1,74 @@
+package limited_swap_manager
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func burstableContainer(request, limit string) *v1.Container {
+	container := &v1.Container{
+		Name: "test",
+		Resources: v1.ResourceRequirements{
+			Requests: v1.ResourceList{},
+			Limits:   v1.ResourceList{},
+		},
+	}
+	if request != "" {
+		container.Resources.Requests[v1.ResourceMemory] = resource.MustParse(request)
+	}
+	if limit != "" {
+		container.Resources.Limits[v1.ResourceMemory] = resource.MustParse(limit)
+	}
+	return container
+}
+
+var _ = Describe("Swap policies", func() {
+	capacity := NodeCapacity{
+		Memory: 16 * 1024 * 1024 * 1024,
+		Swap:   8 * 1024 * 1024 * 1024,
+	}
+
+	It("should default to proportional-to-request", func() {
+		policy, err := NewSwapPolicy("", SwapPolicyOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(policy.Name()).To(Equal(ProportionalToRequestPolicy))
+	})
+
+	It("should reject unknown policies", func() {
+		_, err := NewSwapPolicy("no-such-policy", SwapPolicyOptions{})
+		Expect(err).To(MatchError(ContainSubstring("unknown swap policy")))
+	})
+
+	It("should require a positive cap for fixed-cap", func() {
+		_, err := NewSwapPolicy(FixedCapPolicy, SwapPolicyOptions{})
+		Expect(err).To(HaveOccurred())
+	})
+
+	DescribeTable("should calculate the swap limit", func(name string, opts SwapPolicyOptions, container *v1.Container, expected int64) {
+		policy, err := NewSwapPolicy(name, opts)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(policy.SwapLimit(container, capacity)).To(Equal(expected))
+	},
+		Entry("proportional-to-request",
+			ProportionalToRequestPolicy, SwapPolicyOptions{}, burstableContainer("2Gi", "4Gi"), int64(1024*1024*1024)),
+		Entry("proportional-to-request without limit",
+			ProportionalToRequestPolicy, SwapPolicyOptions{}, burstableContainer("4Gi", ""), int64(2*1024*1024*1024)),
+		Entry("proportional-to-limit-minus-request",
+			ProportionalToLimitMinusRequestPolicy, SwapPolicyOptions{}, burstableContainer("2Gi", "6Gi"), int64(2*1024*1024*1024)),
+		Entry("proportional-to-limit-minus-request without limit",
+			ProportionalToLimitMinusRequestPolicy, SwapPolicyOptions{}, burstableContainer("2Gi", ""), int64(0)),
+		Entry("fixed-cap",
+			FixedCapPolicy, SwapPolicyOptions{FixedCap: resource.MustParse("1Gi")}, burstableContainer("2Gi", "4Gi"), int64(1024*1024*1024)),
+		Entry("fixed-cap larger than node swap",
+			FixedCapPolicy, SwapPolicyOptions{FixedCap: resource.MustParse("32Gi")}, burstableContainer("2Gi", "4Gi"), capacity.Swap),
+		Entry("unlimited-for-burstable",
+			UnlimitedForBurstablePolicy, SwapPolicyOptions{}, burstableContainer("2Gi", "4Gi"), UnlimitedSwap),
+	)
+
+	It("should grant no swap when the memory of the node is unknown", func() {
+		Expect(calcSwapForBurstablePods(2*1024*1024*1024, 0, capacity.Swap)).To(BeZero())
+	})
+})
@@ -0,0 +1,217 @@
+package limited_swap_manager
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/opencontainers/runc/libcontainer/cgroups/fscommon"
+	"github.com/openshift-virtualization/wasp-agent/pkg/log"
+	"github.com/openshift-virtualization/wasp-agent/pkg/monitoring/metrics"
+)
+
+// managedContainer is a container whose swap limit was set by the agent
+type managedContainer struct {
+	namespace  string
+	pod        string
+	container  string
+	cgroupPath string
+	// swapLimitValue is the value the agent set the swap limit file of the cgroup to
+	swapLimitValue int64
+	// softLimits are the soft limits the agent throttles the container with
+	softLimits softLimits
+}
+
+// managedContainers tracks the containers managed by the agent by container ID
+type managedContainers struct {
+	lock       sync.Mutex
+	containers map[string]managedContainer
+}
+
+func newManagedContainers() *managedContainers {
+	return &managedContainers{containers: map[string]managedContainer{}}
+}
+
+func (m *managedContainers) add(containerID string, container managedContainer) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.containers[containerID] = container
+}
+
+func (m *managedContainers) get(containerID string) (managedContainer, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	container, ok := m.containers[containerID]
+	return container, ok
+}
+
+func (m *managedContainers) remove(containerID string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.containers, containerID)
+}
+
+func (m *managedContainers) removePod(namespace, pod string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for containerID, container := range m.containers {
+		if container.namespace == namespace && container.pod == pod {
+			delete(m.containers, containerID)
+		}
+	}
+}
+
+func (m *managedContainers) list() map[string]managedContainer {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	containers := make(map[string]managedContainer, len(m.containers))
+	for containerID, container := range m.containers {
+		containers[containerID] = container
+	}
+	return containers
+}
+
+// swapUsage is the swap usage of a cgroup as reported by the cgroup v2 memory controller
+type swapUsage struct {
+	swapCurrent    uint64
+	swapEventsMax  uint64
+	swapEventsFail uint64
+	// swapIn and swapOut are the pswpin and pswpout counters of memory.stat,
+	// they are nil on kernels that do not report them per cgroup
+	swapIn  *uint64
+	swapOut *uint64
+}
+
+func readSwapUsage(dirPath string) (swapUsage, error) {
+	usage := swapUsage{}
+
+	var err error
+	usage.swapCurrent, err = readUintFile(dirPath, "memory.swap.current")
+	if err != nil {
+		return usage, err
+	}
+
+	swapEvents, err := readKeyValueFile(dirPath, "memory.swap.events")
+	if err != nil {
+		return usage, err
+	}
+	usage.swapEventsMax = swapEvents["max"]
+	usage.swapEventsFail = swapEvents["fail"]
+
+	memoryStat, err := readKeyValueFile(dirPath, "memory.stat")
+	if err != nil {
+		return usage, err
+	}
+	if swapIn, ok := memoryStat["pswpin"]; ok {
+		usage.swapIn = &swapIn
+	}
+	if swapOut, ok := memoryStat["pswpout"]; ok {
+		usage.swapOut = &swapOut
+	}
+
+	return usage, nil
+}
+
+func readKeyValueFile(dirPath, file string) (map[string]uint64, error) {
+	content, err := cgroups.ReadFile(dirPath, file)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]uint64{}
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		key, value, err := fscommon.ParseKeyValue(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	return values, scanner.Err()
+}
+
+// collectSwapUsage samples the swap usage of all the managed containers and exports it.
+// Containers whose cgroup no longer exists are forgotten.
+func (lsm *LimitedSwapManager) collectSwapUsage() {
+	var samples []metrics.ContainerSwapUsage
+	for containerID, container := range lsm.managedContainers.list() {
+		usage, err := readSwapUsage(container.cgroupPath)
+		if errors.Is(err, os.ErrNotExist) {
+			lsm.managedContainers.remove(containerID)
+			continue
+		} else if err != nil {
+			log.Log.Infof("LimitedSwapManager: couldn't read swap usage of %s/%s/%s: %v", container.namespace, container.pod, container.container, err)
+			continue
+		}
+
+		samples = append(samples, metrics.ContainerSwapUsage{
+			Namespace:      container.namespace,
+			Pod:            container.pod,
+			Container:      container.container,
+			SwapCurrent:    usage.swapCurrent,
+			SwapEventsMax:  usage.swapEventsMax,
+			SwapEventsFail: usage.swapEventsFail,
+			SwapIn:         usage.swapIn,
+			SwapOut:        usage.swapOut,
+		})
+	}
+	metrics.SetContainerSwapUsage(samples)
+}
+
+// ContainerMemoryUsage is the memory and swap usage of a container whose swap is managed by the agent
+type ContainerMemoryUsage struct {
+	Namespace string
+	Pod       string
+	Container string
+	// Memory is the memory.current value of the container cgroup
+	Memory uint64
+	// Swap is the memory.swap.current value of the container cgroup
+	Swap uint64
+}
+
+// ContainerMemoryUsage samples the memory and swap usage of the managed containers, on cgroup v2 only.
+// Containers whose cgroup no longer exists are skipped, they are forgotten by the next swap usage collection.
+func (lsm *LimitedSwapManager) ContainerMemoryUsage() ([]ContainerMemoryUsage, error) {
+	if lsm.cgroupMode != cgroupV2 {
+		return nil, fmt.Errorf("the memory usage of the containers is only sampled on cgroup v2")
+	}
+	var usages []ContainerMemoryUsage
+	for _, container := range lsm.managedContainers.list() {
+		usage, err := readMemoryUsage(container.cgroupPath)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			log.Log.Infof("LimitedSwapManager: couldn't read memory usage of %s/%s/%s: %v", container.namespace, container.pod, container.container, err)
+			continue
+		}
+		usage.Namespace = container.namespace
+		usage.Pod = container.pod
+		usage.Container = container.container
+		usages = append(usages, usage)
+	}
+	return usages, nil
+}
+
+func readMemoryUsage(dirPath string) (ContainerMemoryUsage, error) {
+	usage := ContainerMemoryUsage{}
+	var err error
+	usage.Memory, err = readUintFile(dirPath, "memory.current")
+	if err != nil {
+		return usage, err
+	}
+	usage.Swap, err = readUintFile(dirPath, "memory.swap.current")
+	return usage, err
+}
+
+func readUintFile(dirPath, file string) (uint64, error) {
+	content, err := cgroups.ReadFile(dirPath, file)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(content), 10, 64)
+}
@@ -0,0 +1,75 @@
+package limited_swap_manager
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func writeCgroupFiles(dirPath string, files map[string]string) {
+	Expect(os.MkdirAll(dirPath, 0755)).To(Succeed())
+	for name, content := range files {
+		Expect(os.WriteFile(filepath.Join(dirPath, name), []byte(content), 0644)).To(Succeed())
+	}
+}
+
+var _ = Describe("Swap usage", func() {
+	var cgroupPath string
+
+	BeforeEach(func() {
+		cgroupPath = filepath.Join(GinkgoT().TempDir(), "crio-1234.scope")
+		writeCgroupFiles(cgroupPath, map[string]string{
+			"memory.swap.current": "4096\n",
+			"memory.swap.events":  "high 0\nmax 3\nfail 1\n",
+			"memory.stat":         "anon 1024\nfile 2048\npswpin 7\npswpout 11\n",
+		})
+	})
+
+	It("should read the swap usage of a cgroup", func() {
+		usage, err := readSwapUsage(cgroupPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(usage.swapCurrent).To(Equal(uint64(4096)))
+		Expect(usage.swapEventsMax).To(Equal(uint64(3)))
+		Expect(usage.swapEventsFail).To(Equal(uint64(1)))
+		Expect(usage.swapIn).To(HaveValue(Equal(uint64(7))))
+		Expect(usage.swapOut).To(HaveValue(Equal(uint64(11))))
+	})
+
+	It("should tolerate kernels without per cgroup swap counters", func() {
+		writeCgroupFiles(cgroupPath, map[string]string{"memory.stat": "anon 1024\nfile 2048\n"})
+		usage, err := readSwapUsage(cgroupPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(usage.swapIn).To(BeNil())
+		Expect(usage.swapOut).To(BeNil())
+	})
+
+	It("should forget containers whose cgroup was removed", func() {
+		lsm := &LimitedSwapManager{managedContainers: newManagedContainers()}
+		lsm.managedContainers.add("running", managedContainer{namespace: "ns", pod: "pod", container: "a", cgroupPath: cgroupPath})
+		lsm.managedContainers.add("exited", managedContainer{namespace: "ns", pod: "pod", container: "b", cgroupPath: filepath.Join(cgroupPath, "missing")})
+
+		lsm.collectSwapUsage()
+
+		Expect(lsm.managedContainers.list()).To(HaveKey("running"))
+		Expect(lsm.managedContainers.list()).ToNot(HaveKey("exited"))
+	})
+
+	It("should sample the memory usage of the managed containers", func() {
+		writeCgroupFiles(cgroupPath, map[string]string{"memory.current": "8192\n"})
+		lsm := &LimitedSwapManager{managedContainers: newManagedContainers(), cgroupMode: cgroupV2}
+		lsm.managedContainers.add("running", managedContainer{namespace: "ns", pod: "pod", container: "a", cgroupPath: cgroupPath})
+		lsm.managedContainers.add("exited", managedContainer{namespace: "ns", pod: "pod", container: "b", cgroupPath: filepath.Join(cgroupPath, "missing")})
+
+		usages, err := lsm.ContainerMemoryUsage()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(usages).To(ConsistOf(ContainerMemoryUsage{Namespace: "ns", Pod: "pod", Container: "a", Memory: 8192, Swap: 4096}))
+	})
+
+	It("should not sample the memory usage on cgroup v1", func() {
+		lsm := &LimitedSwapManager{managedContainers: newManagedContainers(), cgroupMode: cgroupV1}
+		_, err := lsm.ContainerMemoryUsage()
+		Expect(err).To(HaveOccurred())
+	})
+})
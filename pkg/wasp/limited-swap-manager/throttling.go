@@ -0,0 +1,123 @@
+package limited_swap_manager
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/openshift-virtualization/wasp-agent/pkg/log"
+	"github.com/openshift-virtualization/wasp-agent/pkg/monitoring/metrics"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	swapHighFile   = "memory.swap.high"
+	memoryHighFile = "memory.high"
+)
+
+// Throttling sets the soft limits of the containers that get swap, so that they are throttled and their memory is reclaimed
+// gradually before they reach their hard limits. The soft limits only exist on cgroup v2.
+type Throttling struct {
+	// SwapHighPercent sets memory.swap.high to this percentage of the swap limit of the containers, 0 leaves it unmanaged
+	SwapHighPercent int
+	// MemoryHighPercent sets memory.high between the memory request (0) and the memory limit (100) of the containers,
+	// 0 leaves it unmanaged
+	MemoryHighPercent int
+}
+
+// softLimits is whether the agent set the soft limits of a container to a value other than max
+type softLimits struct {
+	swapHigh   bool
+	memoryHigh bool
+}
+
+// swapHigh returns the memory.swap.high value of a container with swapLimit, UnlimitedSwap when swap is not throttled
+func (t Throttling) swapHigh(swapLimit int64) int64 {
+	if t.SwapHighPercent == 0 || swapLimit == UnlimitedSwap || swapLimit == 0 {
+		return UnlimitedSwap
+	}
+	return swapLimit * int64(t.SwapHighPercent) / 100
+}
+
+// memoryHigh returns the memory.high value of a container with swapLimit, UnlimitedSwap when memory is not throttled.
+// Containers without swap, or without room between their memory request and limit, are not throttled.
+func (t Throttling) memoryHigh(container *v1.Container, swapLimit int64) int64 {
+	if t.MemoryHighPercent == 0 || swapLimit == 0 {
+		return UnlimitedSwap
+	}
+	request := container.Resources.Requests.Memory().Value()
+	limit := container.Resources.Limits.Memory().Value()
+	if limit == 0 || limit <= request {
+		return UnlimitedSwap
+	}
+	return request + (limit-request)*int64(t.MemoryHighPercent)/100
+}
+
+// applyThrottling sets the soft limits of a container from its swap limit. The soft limits are reverted to max when
+// the container loses its swap grant, and when throttling is turned off for the containers the agent throttled.
+func (lsm *LimitedSwapManager) applyThrottling(cgroup, dirPath string, container *v1.Container, swapLimit int64, throttling Throttling, previous softLimits) (softLimits, error) {
+	applied := softLimits{}
+	var err error
+	if throttling.SwapHighPercent != 0 || previous.swapHigh {
+		applied.swapHigh, err = lsm.applySoftLimit(cgroup, dirPath, swapHighFile, throttling.swapHigh(swapLimit))
+		if err != nil {
+			return previous, err
+		}
+	}
+	if throttling.MemoryHighPercent != 0 || previous.memoryHigh {
+		applied.memoryHigh, err = lsm.applySoftLimit(cgroup, dirPath, memoryHighFile, throttling.memoryHigh(container, swapLimit))
+		if err != nil {
+			return previous, err
+		}
+	}
+	return applied, nil
+}
+
+// applySoftLimit sets a soft limit file of a cgroup unless it already has the value, UnlimitedSwap is written as max.
+// It returns whether the file was set to a value other than max. In dry-run mode the change is only logged.
+func (lsm *LimitedSwapManager) applySoftLimit(cgroup, dirPath, file string, value int64) (bool, error) {
+	current, err := readSoftLimit(dirPath, file)
+	if err != nil {
+		return false, err
+	}
+	if current == value {
+		return value != UnlimitedSwap, nil
+	}
+	if lsm.dryRun {
+		log.Log.Infof("LimitedSwapManager: dry-run: would change %s of %s from %s to %s",
+			file, cgroup, formatSwapLimitValue(current), formatSwapLimitValue(value))
+		return false, nil
+	}
+	return value != UnlimitedSwap, cgroups.WriteFile(dirPath, file, formatSwapLimitValue(value))
+}
+
+func readSoftLimit(dirPath, file string) (int64, error) {
+	content, err := cgroups.ReadFile(dirPath, file)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(content)
+	if value == "max" {
+		return UnlimitedSwap, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// setContainerSoftLimitMetrics records the soft limits managed by the agent
+func setContainerSoftLimitMetrics(pod *v1.Pod, container *v1.Container, swapLimit int64, throttling Throttling) {
+	if throttling.SwapHighPercent != 0 {
+		metrics.SetContainerSwapHigh(pod.Namespace, pod.Name, container.Name, throttling.swapHigh(swapLimit))
+	}
+	if throttling.MemoryHighPercent != 0 {
+		metrics.SetContainerMemoryHigh(pod.Namespace, pod.Name, container.Name, throttling.memoryHigh(container, swapLimit))
+	}
+}
+
+// restoreThrottling reverts the soft limits managed by the agent to max
+func (lsm *LimitedSwapManager) restoreThrottling(cgroup, dirPath string, throttling Throttling) error {
+	_, err := lsm.applyThrottling(cgroup, dirPath, nil, 0, Throttling{}, softLimits{
+		swapHigh:   throttling.SwapHighPercent != 0,
+		memoryHigh: throttling.MemoryHighPercent != 0,
+	})
+	return err
+}
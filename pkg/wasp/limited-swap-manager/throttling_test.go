@@ -0,0 +1,105 @@
+package limited_swap_manager
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Throttling", func() {
+	const gi = 1024 * 1024 * 1024
+
+	var (
+		lsm       *LimitedSwapManager
+		cgroupDir string
+	)
+
+	readFile := func(file string) string {
+		content, err := os.ReadFile(filepath.Join(cgroupDir, file))
+		Expect(err).ToNot(HaveOccurred())
+		return string(content)
+	}
+
+	BeforeEach(func() {
+		lsm = &LimitedSwapManager{cgroupMode: cgroupV2}
+		cgroupDir = filepath.Join(GinkgoT().TempDir(), "crio-1234.scope")
+		writeCgroupFiles(cgroupDir, map[string]string{
+			swapHighFile:   "max\n",
+			memoryHighFile: "max\n",
+		})
+	})
+
+	DescribeTable("should derive memory.swap.high from the swap limit", func(percent int, swapLimit, expected int64) {
+		Expect(Throttling{SwapHighPercent: percent}.swapHigh(swapLimit)).To(Equal(expected))
+	},
+		Entry("when throttling swap", 80, int64(10*gi), int64(8*gi)),
+		Entry("when not throttling swap", 0, int64(10*gi), UnlimitedSwap),
+		Entry("for containers without swap", 80, int64(0), UnlimitedSwap),
+		Entry("for containers with unlimited swap", 80, UnlimitedSwap, UnlimitedSwap),
+	)
+
+	DescribeTable("should place memory.high between the memory request and limit", func(percent int, request, limit string, swapLimit, expected int64) {
+		Expect(Throttling{MemoryHighPercent: percent}.memoryHigh(burstableContainer(request, limit), swapLimit)).To(Equal(expected))
+	},
+		Entry("when throttling memory", 50, "2Gi", "6Gi", int64(gi), int64(4*gi)),
+		Entry("when not throttling memory", 0, "2Gi", "6Gi", int64(gi), UnlimitedSwap),
+		Entry("for containers without a memory limit", 50, "2Gi", "", int64(gi), UnlimitedSwap),
+		Entry("for containers without swap", 50, "2Gi", "6Gi", int64(0), UnlimitedSwap),
+	)
+
+	It("should set the soft limits of a container", func() {
+		throttling := Throttling{SwapHighPercent: 50, MemoryHighPercent: 50}
+		applied, err := lsm.applyThrottling("container", cgroupDir, burstableContainer("2Gi", "6Gi"), 2*gi, throttling, softLimits{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(applied).To(Equal(softLimits{swapHigh: true, memoryHigh: true}))
+		Expect(readFile(swapHighFile)).To(Equal("1073741824"))
+		Expect(readFile(memoryHighFile)).To(Equal("4294967296"))
+	})
+
+	It("should revert the soft limits when the container loses its swap", func() {
+		throttling := Throttling{SwapHighPercent: 50, MemoryHighPercent: 50}
+		previous, err := lsm.applyThrottling("container", cgroupDir, burstableContainer("2Gi", "6Gi"), 2*gi, throttling, softLimits{})
+		Expect(err).ToNot(HaveOccurred())
+
+		applied, err := lsm.applyThrottling("container", cgroupDir, burstableContainer("2Gi", "6Gi"), 0, throttling, previous)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(applied).To(Equal(softLimits{}))
+		Expect(readFile(swapHighFile)).To(Equal("max"))
+		Expect(readFile(memoryHighFile)).To(Equal("max"))
+	})
+
+	It("should revert the soft limits it set when throttling is turned off", func() {
+		previous, err := lsm.applyThrottling("container", cgroupDir, burstableContainer("2Gi", "6Gi"), 2*gi, Throttling{SwapHighPercent: 50}, softLimits{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(previous).To(Equal(softLimits{swapHigh: true}))
+
+		_, err = lsm.applyThrottling("container", cgroupDir, burstableContainer("2Gi", "6Gi"), 2*gi, Throttling{}, previous)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(readFile(swapHighFile)).To(Equal("max"))
+	})
+
+	It("should not touch the soft limits it does not manage", func() {
+		writeCgroupFiles(cgroupDir, map[string]string{memoryHighFile: "3221225472\n"})
+		_, err := lsm.applyThrottling("container", cgroupDir, burstableContainer("2Gi", "6Gi"), 2*gi, Throttling{SwapHighPercent: 50}, softLimits{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(readFile(memoryHighFile)).To(Equal("3221225472\n"))
+	})
+
+	It("should not change the cgroup in dry-run mode", func() {
+		lsm.dryRun = true
+		applied, err := lsm.applyThrottling("container", cgroupDir, burstableContainer("2Gi", "6Gi"), 2*gi, Throttling{SwapHighPercent: 50, MemoryHighPercent: 50}, softLimits{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(applied).To(Equal(softLimits{}))
+		Expect(readFile(swapHighFile)).To(Equal("max\n"))
+		Expect(readFile(memoryHighFile)).To(Equal("max\n"))
+	})
+
+	It("should revert the configured soft limits when the agent is uninstalled", func() {
+		writeCgroupFiles(cgroupDir, map[string]string{swapHighFile: "1073741824\n", memoryHighFile: "3221225472\n"})
+		Expect(lsm.restoreThrottling("container", cgroupDir, Throttling{SwapHighPercent: 50})).To(Succeed())
+		Expect(readFile(swapHighFile)).To(Equal("max"))
+		Expect(readFile(memoryHighFile)).To(Equal("3221225472\n"))
+	})
+})
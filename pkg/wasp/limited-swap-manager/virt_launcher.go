@@ -0,0 +1,110 @@
+package limited_swap_manager
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+const (
+	virtLauncherLabelValue = "virt-launcher"
+	computeContainerName   = "compute"
+	// VirtLauncherSwapPolicy is the swap policy applied to the virt-launcher pods of KubeVirt VMIs
+	VirtLauncherSwapPolicy = "virt-launcher"
+)
+
+// vmiOwnerName returns the name of the VMI that owns a virt-launcher pod
+func vmiOwnerName(pod *v1.Pod) (string, bool) {
+	if pod.Labels[kubevirtv1.AppLabel] != virtLauncherLabelValue {
+		return "", false
+	}
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == kubevirtv1.VirtualMachineInstanceGroupVersionKind.Kind &&
+			owner.APIVersion == kubevirtv1.GroupVersion.String() {
+			return owner.Name, true
+		}
+	}
+	return "", false
+}
+
+// vmiGuestMemory returns the memory visible inside the guest, which defaults to the memory request of the VMI
+func vmiGuestMemory(vmi *kubevirtv1.VirtualMachineInstance) int64 {
+	if memory := vmi.Spec.Domain.Memory; memory != nil && memory.Guest != nil {
+		return memory.Guest.Value()
+	}
+	return vmi.Spec.Domain.Resources.Requests.Memory().Value()
+}
+
+// virtLauncherSwapPolicy grants the compute container of a virt-launcher pod the part of the guest memory
+// that is not backed by its memory request, i.e. the memory overcommitted by KubeVirt, unless the strategy
+// of a SwapPolicy object applies to the pod. The helper containers of the pod get no swap.
+type virtLauncherSwapPolicy struct {
+	guestMemory int64
+	// strategy is the swap policy of the compute container set by a SwapPolicy object, if any
+	strategy SwapPolicy
+}
+
+func (p *virtLauncherSwapPolicy) Name() string {
+	return VirtLauncherSwapPolicy
+}
+
+func (p *virtLauncherSwapPolicy) SwapLimit(container *v1.Container, capacity NodeCapacity) int64 {
+	if container.Name != computeContainerName {
+		return 0
+	}
+	if p.strategy != nil {
+		return p.strategy.SwapLimit(container, capacity)
+	}
+	swapLimit := p.guestMemory - container.Resources.Requests.Memory().Value()
+	if swapLimit <= 0 {
+		return 0
+	}
+	if swapLimit > capacity.Swap {
+		return capacity.Swap
+	}
+	return swapLimit
+}
+
+// virtLauncherSwapConfig replaces the swap policy of a virt-launcher pod by the virt-launcher policy,
+// the other fields of the configuration, e.g. the maxSwap of a SwapPolicy object, still apply.
+// The configuration is returned unchanged for other pods, or when KubeVirt is not installed.
+// found is false when the VMI of a virt-launcher pod is not known yet, its containers then get no swap
+// until vmiChanged reconciles the pod.
+func (lsm *LimitedSwapManager) virtLauncherSwapConfig(pod *v1.Pod, swapConfig podSwapConfig) (config podSwapConfig, found bool, err error) {
+	vmiName, ok := vmiOwnerName(pod)
+	if !ok || lsm.vmiStore == nil {
+		return swapConfig, true, nil
+	}
+	obj, exists, err := lsm.vmiStore.GetByKey(pod.Namespace + "/" + vmiName)
+	if err != nil || !exists {
+		// the VMI is added to the informer once it is scheduled on the node
+		return swapConfig, false, err
+	}
+	policy := &virtLauncherSwapPolicy{guestMemory: vmiGuestMemory(obj.(*kubevirtv1.VirtualMachineInstance))}
+	if swapConfig.strategySet {
+		policy.strategy = swapConfig.policy
+	}
+	swapConfig.policy = policy
+	return swapConfig, true, nil
+}
+
+// vmiChanged reconciles the virt-launcher pods of a VMI, since their swap depends on its guest memory
+func (lsm *LimitedSwapManager) vmiChanged(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	vmi, ok := obj.(*kubevirtv1.VirtualMachineInstance)
+	if !ok {
+		return
+	}
+	pods, err := lsm.podLister.Pods(vmi.Namespace).List(labels.SelectorFromSet(labels.Set{kubevirtv1.AppLabel: virtLauncherLabelValue}))
+	if err != nil {
+		return
+	}
+	for _, pod := range pods {
+		if name, _ := vmiOwnerName(pod); name == vmi.Name {
+			lsm.enqueuePod(pod)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package limited_swap_manager
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1lister "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	kubevirtv1 "kubevirt.io/api/core/v1"
+)
+
+var _ = Describe("virt-launcher pods", func() {
+	const gi = 1024 * 1024 * 1024
+	capacity := NodeCapacity{Memory: 64 * gi, Swap: 16 * gi}
+
+	var (
+		lsm      *LimitedSwapManager
+		vmiStore cache.Store
+		pod      *v1.Pod
+		compute  *v1.Container
+	)
+
+	newVMI := func(request string, guest *resource.Quantity) *kubevirtv1.VirtualMachineInstance {
+		vmi := &kubevirtv1.VirtualMachineInstance{
+			ObjectMeta: metav1.ObjectMeta{Name: "vm", Namespace: "vms"},
+		}
+		vmi.Spec.Domain.Resources.Requests = v1.ResourceList{v1.ResourceMemory: resource.MustParse(request)}
+		if guest != nil {
+			vmi.Spec.Domain.Memory = &kubevirtv1.Memory{Guest: guest}
+		}
+		return vmi
+	}
+
+	BeforeEach(func() {
+		vmiStore = cache.NewStore(cache.MetaNamespaceKeyFunc)
+		lsm = &LimitedSwapManager{vmiStore: vmiStore}
+
+		compute = burstableContainer("6Gi", "")
+		compute.Name = computeContainerName
+		pod = &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "virt-launcher-vm-abcde",
+				Namespace: "vms",
+				Labels:    map[string]string{kubevirtv1.AppLabel: virtLauncherLabelValue},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: kubevirtv1.GroupVersion.String(),
+					Kind:       "VirtualMachineInstance",
+					Name:       "vm",
+				}},
+			},
+			Spec: v1.PodSpec{Containers: []v1.Container{*compute}},
+		}
+	})
+
+	It("should detect the VMI owning a virt-launcher pod", func() {
+		name, ok := vmiOwnerName(pod)
+		Expect(ok).To(BeTrue())
+		Expect(name).To(Equal("vm"))
+
+		pod.Labels = nil
+		_, ok = vmiOwnerName(pod)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should use the guest memory of the VMI, or its memory request", func() {
+		guest := resource.MustParse("12Gi")
+		Expect(vmiGuestMemory(newVMI("8Gi", &guest))).To(Equal(int64(12 * gi)))
+		Expect(vmiGuestMemory(newVMI("8Gi", nil))).To(Equal(int64(8 * gi)))
+	})
+
+	It("should grant the compute container the overcommitted guest memory", func() {
+		guest := resource.MustParse("9Gi")
+		Expect(vmiStore.Add(newVMI("6Gi", &guest))).To(Succeed())
+
+		config, found, err := lsm.virtLauncherSwapConfig(pod, podSwapConfig{policy: &proportionalToRequest{}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(config.policy.Name()).To(Equal(VirtLauncherSwapPolicy))
+		Expect(config.policy.SwapLimit(compute, capacity)).To(Equal(int64(3 * gi)))
+
+		helper := burstableContainer("100Mi", "")
+		helper.Name = "guest-console-log"
+		Expect(config.policy.SwapLimit(helper, capacity)).To(BeZero())
+	})
+
+	It("should grant no swap when the guest memory is not overcommitted", func() {
+		policy := &virtLauncherSwapPolicy{guestMemory: 4 * gi}
+		Expect(policy.SwapLimit(compute, capacity)).To(BeZero())
+	})
+
+	It("should cap the swap of the compute container at the node swap", func() {
+		policy := &virtLauncherSwapPolicy{guestMemory: 64 * gi}
+		Expect(policy.SwapLimit(compute, capacity)).To(Equal(capacity.Swap))
+	})
+
+	It("should apply the strategy of a SwapPolicy object to the compute container only", func() {
+		Expect(vmiStore.Add(newVMI("6Gi", nil))).To(Succeed())
+		strategy, err := NewSwapPolicy(FixedCapPolicy, SwapPolicyOptions{FixedCap: resource.MustParse("2Gi")})
+		Expect(err).ToNot(HaveOccurred())
+
+		config, found, err := lsm.virtLauncherSwapConfig(pod, podSwapConfig{policy: strategy, strategySet: true})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(config.policy.SwapLimit(compute, capacity)).To(Equal(int64(2 * gi)))
+		Expect(config.policy.SwapLimit(&v1.Container{Name: "hotplug-disk"}, capacity)).To(BeZero())
+	})
+
+	It("should wait for the VMI without failing", func() {
+		_, found, err := lsm.virtLauncherSwapConfig(pod, podSwapConfig{policy: &proportionalToRequest{}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+	})
+
+	It("should not count the pod towards the initial sync until the VMI is known", func() {
+		policy, err := NewSwapPolicy(DefaultSwapPolicy, SwapPolicyOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		podIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		Expect(podIndexer.Add(pod)).To(Succeed())
+		lsm.podLister = v1lister.NewPodLister(podIndexer)
+		lsm.podQueue = workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+		lsm.swapPolicyResolver = newSwapPolicyResolver(nil, nil, policy)
+		lsm.initialSync = newInitialSync()
+		lsm.nodeCapacity.set(capacity)
+		DeferCleanup(lsm.podQueue.ShutDown)
+		lsm.startInitialSync()
+
+		key := pod.Namespace + "/" + pod.Name
+		lsm.podQueue.Add(key)
+		Expect(lsm.Execute()).To(BeTrue())
+		Expect(lsm.InitialSyncDone()).ToNot(BeClosed())
+		Expect(lsm.podQueue.NumRequeues(key)).To(BeZero())
+
+		Expect(vmiStore.Add(newVMI("6Gi", nil))).To(Succeed())
+		lsm.podQueue.Add(key)
+		Expect(lsm.Execute()).To(BeTrue())
+		Expect(lsm.InitialSyncDone()).To(BeClosed())
+	})
+
+	It("should keep the swap policy of other pods, or when KubeVirt is not installed", func() {
+		policy := &proportionalToRequest{}
+		config, found, err := lsm.virtLauncherSwapConfig(&v1.Pod{}, podSwapConfig{policy: policy})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(config.policy).To(BeIdenticalTo(policy))
+
+		lsm.vmiStore = nil
+		config, found, err = lsm.virtLauncherSwapConfig(pod, podSwapConfig{policy: policy})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(config.policy).To(BeIdenticalTo(policy))
+	})
+})
@@ -0,0 +1,34 @@
+package wasp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/openshift-virtualization/wasp-agent/pkg/log"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// serveMetrics serves the agent metrics until ctx is done
+func serveMetrics(ctx context.Context, bindAddress string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{
+		Addr:              bindAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	log.Log.Infof("serving metrics on %s", bindAddress)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Log.Errorf("metrics server failed: %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package node_readiness
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openshift-virtualization/wasp-agent/pkg/log"
+	v1 "k8s.io/api/core/v1"
+	kapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	// ConditionType is the node condition published by the agent, true once it manages the swap of the pods on the node
+	ConditionType v1.NodeConditionType = "WaspReady"
+	// StartupTaintKey keeps new pods off the node until the agent manages their swap
+	StartupTaintKey = "wasp.io/not-ready"
+
+	ReasonStarting    = "AgentStarting"
+	ReasonReady       = "AgentReady"
+	ReasonNoSwap      = "NoSwap"
+	ReasonDryRun      = "DryRun"
+	ReasonUninstalled = "AgentUninstalled"
+	ReasonStopping    = "AgentStopping"
+
+	maxConflictRetries = 5
+	callTimeout        = 30 * time.Second
+)
+
+// taintChange is the change of the startup taint made along with an update of the condition
+type taintChange int
+
+const (
+	keepTaint taintChange = iota
+	addTaint
+	removeTaint
+)
+
+// NodeReadiness publishes the WaspReady condition of the node and optionally manages the startup taint.
+// The taint is only added when it is managed but it is always removed, so that a taint left by a previous
+// run of the agent does not keep the pods off the node once the startup taint is disabled.
+type NodeReadiness struct {
+	nodes       corev1client.NodeInterface
+	nodeName    string
+	manageTaint bool
+}
+
+func New(nodes corev1client.NodeInterface, nodeName string, manageTaint bool) *NodeReadiness {
+	return &NodeReadiness{
+		nodes:       nodes,
+		nodeName:    nodeName,
+		manageTaint: manageTaint,
+	}
+}
+
+// SetNotReady sets the WaspReady condition to false and adds the startup taint when it is managed
+func (n *NodeReadiness) SetNotReady(reason, message string) error {
+	return n.update(v1.ConditionFalse, reason, message, addTaint)
+}
+
+// SetStopped sets the WaspReady condition to false and leaves the startup taint as it is
+func (n *NodeReadiness) SetStopped(reason, message string) error {
+	return n.update(v1.ConditionFalse, reason, message, keepTaint)
+}
+
+// SetUninstalled sets the WaspReady condition to false and removes the startup taint,
+// since no agent will run on the node to remove it
+func (n *NodeReadiness) SetUninstalled(message string) error {
+	return n.update(v1.ConditionFalse, ReasonUninstalled, message, removeTaint)
+}
+
+// SetReady sets the WaspReady condition to true and removes the startup taint
+func (n *NodeReadiness) SetReady(message string) error {
+	return n.update(v1.ConditionTrue, ReasonReady, message, removeTaint)
+}
+
+func (n *NodeReadiness) update(status v1.ConditionStatus, reason, message string, taint taintChange) error {
+	for attempt := 0; ; attempt++ {
+		err := n.tryUpdate(status, reason, message, taint)
+		if kapierrors.IsConflict(err) && attempt < maxConflictRetries {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to update the readiness of node %s: %w", n.nodeName, err)
+		}
+		log.Log.Infof("node %s: %s=%s, reason: %s", n.nodeName, ConditionType, status, reason)
+		return nil
+	}
+}
+
+func (n *NodeReadiness) tryUpdate(status v1.ConditionStatus, reason, message string, taint taintChange) error {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+
+	node, err := n.nodes.Get(ctx, n.nodeName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	// the condition becomes true before the taint is removed and false after it is added,
+	// so that the node is never left without the taint while the condition is false
+	if taint != addTaint && setCondition(node, status, reason, message, metav1.Now()) {
+		if node, err = n.nodes.UpdateStatus(ctx, node, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+	if n.changeTaint(node, taint) {
+		if node, err = n.nodes.Update(ctx, node, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+	if taint == addTaint && setCondition(node, status, reason, message, metav1.Now()) {
+		_, err = n.nodes.UpdateStatus(ctx, node, metav1.UpdateOptions{})
+	}
+	return err
+}
+
+// changeTaint applies a change of the startup taint to the node, it returns whether the node changed
+func (n *NodeReadiness) changeTaint(node *v1.Node, taint taintChange) bool {
+	switch taint {
+	case addTaint:
+		return n.manageTaint && setStartupTaint(node, true)
+	case removeTaint:
+		return setStartupTaint(node, false)
+	}
+	return false
+}
+
+// setCondition sets the WaspReady condition of the node, it returns whether the node changed
+func setCondition(node *v1.Node, status v1.ConditionStatus, reason, message string, now metav1.Time) bool {
+	condition := v1.NodeCondition{
+		Type:               ConditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		LastHeartbeatTime:  now,
+		LastTransitionTime: now,
+	}
+	for i, existing := range node.Status.Conditions {
+		if existing.Type != ConditionType {
+			continue
+		}
+		if existing.Status == status && existing.Reason == reason && existing.Message == message {
+			return false
+		}
+		if existing.Status == status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+		node.Status.Conditions[i] = condition
+		return true
+	}
+	node.Status.Conditions = append(node.Status.Conditions, condition)
+	return true
+}
+
+// setStartupTaint adds or removes the startup taint of the node, it returns whether the node changed
+func setStartupTaint(node *v1.Node, tainted bool) bool {
+	for i, taint := range node.Spec.Taints {
+		if taint.Key != StartupTaintKey {
+			continue
+		}
+		if tainted {
+			return false
+		}
+		node.Spec.Taints = append(node.Spec.Taints[:i], node.Spec.Taints[i+1:]...)
+		return true
+	}
+	if !tainted {
+		return false
+	}
+	node.Spec.Taints = append(node.Spec.Taints, v1.Taint{
+		Key:    StartupTaintKey,
+		Effect: v1.TaintEffectNoSchedule,
+	})
+	return true
+}
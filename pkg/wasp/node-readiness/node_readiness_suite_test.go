@@ -0,0 +1,13 @@
+package node_readiness
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestNodeReadiness(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "NodeReadiness Suite")
+}
@@ -0,0 +1,146 @@
+package node_readiness
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	v1 "k8s.io/api/core/v1"
+	kapierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// fakeNodes stores a single node, it fails the first conflicts updates with a conflict
+type fakeNodes struct {
+	corev1client.NodeInterface
+	node      *v1.Node
+	conflicts int
+	updates   []string
+}
+
+func (f *fakeNodes) Get(_ context.Context, _ string, _ metav1.GetOptions) (*v1.Node, error) {
+	return f.node.DeepCopy(), nil
+}
+
+func (f *fakeNodes) Update(_ context.Context, node *v1.Node, _ metav1.UpdateOptions) (*v1.Node, error) {
+	return f.store("spec", node)
+}
+
+func (f *fakeNodes) UpdateStatus(_ context.Context, node *v1.Node, _ metav1.UpdateOptions) (*v1.Node, error) {
+	return f.store("status", node)
+}
+
+func (f *fakeNodes) store(update string, node *v1.Node) (*v1.Node, error) {
+	if f.conflicts > 0 {
+		f.conflicts--
+		return nil, kapierrors.NewConflict(schema.GroupResource{Resource: "nodes"}, node.Name, nil)
+	}
+	f.updates = append(f.updates, update)
+	f.node = node.DeepCopy()
+	return node, nil
+}
+
+var _ = Describe("Node readiness", func() {
+	var nodes *fakeNodes
+
+	BeforeEach(func() {
+		nodes = &fakeNodes{node: &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node01"}}}
+	})
+
+	condition := func() *v1.NodeCondition {
+		for i := range nodes.node.Status.Conditions {
+			if nodes.node.Status.Conditions[i].Type == ConditionType {
+				return &nodes.node.Status.Conditions[i]
+			}
+		}
+		return nil
+	}
+
+	tainted := func() bool {
+		for _, taint := range nodes.node.Spec.Taints {
+			if taint.Key == StartupTaintKey {
+				return true
+			}
+		}
+		return false
+	}
+
+	It("should publish the condition without touching the taints", func() {
+		readiness := New(nodes, "node01", false)
+		Expect(readiness.SetNotReady(ReasonStarting, "starting")).To(Succeed())
+		Expect(condition().Status).To(Equal(v1.ConditionFalse))
+		Expect(condition().Reason).To(Equal(ReasonStarting))
+		Expect(tainted()).To(BeFalse())
+
+		Expect(readiness.SetReady("ready")).To(Succeed())
+		Expect(condition().Status).To(Equal(v1.ConditionTrue))
+		Expect(nodes.updates).To(Equal([]string{"status", "status"}))
+	})
+
+	It("should add the startup taint before the condition becomes false and remove it after it becomes true", func() {
+		readiness := New(nodes, "node01", true)
+		Expect(readiness.SetNotReady(ReasonStarting, "starting")).To(Succeed())
+		Expect(tainted()).To(BeTrue())
+		Expect(nodes.updates).To(Equal([]string{"spec", "status"}))
+
+		nodes.updates = nil
+		Expect(readiness.SetReady("ready")).To(Succeed())
+		Expect(tainted()).To(BeFalse())
+		Expect(condition().Status).To(Equal(v1.ConditionTrue))
+		Expect(nodes.updates).To(Equal([]string{"status", "spec"}))
+	})
+
+	It("should leave the startup taint as it is when the agent stops", func() {
+		readiness := New(nodes, "node01", true)
+		Expect(readiness.SetStopped(ReasonStopping, "stopping")).To(Succeed())
+		Expect(condition().Reason).To(Equal(ReasonStopping))
+		Expect(tainted()).To(BeFalse())
+
+		Expect(readiness.SetNotReady(ReasonStarting, "starting")).To(Succeed())
+		Expect(readiness.SetStopped(ReasonStopping, "stopping")).To(Succeed())
+		Expect(condition().Status).To(Equal(v1.ConditionFalse))
+		Expect(condition().Reason).To(Equal(ReasonStopping))
+		Expect(tainted()).To(BeTrue())
+	})
+
+	It("should always remove the startup taint when the agent is uninstalled", func() {
+		Expect(New(nodes, "node01", true).SetNotReady(ReasonStarting, "starting")).To(Succeed())
+		Expect(tainted()).To(BeTrue())
+
+		nodes.updates = nil
+		Expect(New(nodes, "node01", false).SetUninstalled("uninstalled")).To(Succeed())
+		Expect(tainted()).To(BeFalse())
+		Expect(condition().Status).To(Equal(v1.ConditionFalse))
+		Expect(condition().Reason).To(Equal(ReasonUninstalled))
+		Expect(nodes.updates).To(Equal([]string{"status", "spec"}))
+	})
+
+	It("should not update the node when nothing changes", func() {
+		readiness := New(nodes, "node01", true)
+		Expect(readiness.SetNotReady(ReasonStarting, "starting")).To(Succeed())
+		nodes.updates = nil
+		Expect(readiness.SetNotReady(ReasonStarting, "starting")).To(Succeed())
+		Expect(nodes.updates).To(BeEmpty())
+	})
+
+	It("should keep the transition time when only the reason changes", func() {
+		readiness := New(nodes, "node01", false)
+		Expect(readiness.SetNotReady(ReasonStarting, "starting")).To(Succeed())
+		transition := metav1.NewTime(condition().LastTransitionTime.Add(-10 * time.Minute))
+		condition().LastTransitionTime = transition
+
+		Expect(readiness.SetNotReady(ReasonNoSwap, "no swap")).To(Succeed())
+		Expect(condition().Reason).To(Equal(ReasonNoSwap))
+		Expect(condition().LastTransitionTime).To(Equal(transition))
+	})
+
+	It("should retry on conflicts", func() {
+		nodes.conflicts = 2
+		Expect(New(nodes, "node01", true).SetNotReady(ReasonStarting, "starting")).To(Succeed())
+		Expect(tainted()).To(BeTrue())
+	})
+})
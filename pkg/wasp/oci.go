@@ -3,6 +3,7 @@ package wasp
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"text/template"
 
@@ -20,9 +21,9 @@ type hookRenderer interface {
 	Render() error
 }
 
-func setOCIHook(suffix string) error {
-	scriptPath := consts.HookScriptPath(suffix)
-	configPath := consts.HookConfigPath(suffix)
+func setOCIHook(scriptDir, configDir, suffix string) error {
+	scriptPath := consts.HookScriptPathIn(scriptDir, suffix)
+	configPath := consts.HookConfigPathIn(configDir, suffix)
 
 	if err := setupHookScript(scriptPath); err != nil {
 		return err
@@ -35,8 +36,21 @@ func setOCIHook(suffix string) error {
 	return nil
 }
 
-func cleanupOCIHook(suffix string) {
-	cleanupFiles(consts.HookConfigPath(suffix), consts.HookScriptPath(suffix))
+func cleanupOCIHook(scriptDir, configDir, suffix string) {
+	cleanupFiles(consts.HookConfigPathIn(configDir, suffix), consts.HookScriptPathIn(scriptDir, suffix))
+}
+
+// cleanupAllOCIHooks removes the hooks installed by every agent pod that ran on the node,
+// including the ones left behind by agent pods that did not shut down cleanly
+func cleanupAllOCIHooks(scriptDir, configDir string) {
+	for _, pattern := range []string{consts.HookConfigPathIn(configDir, "*"), consts.HookScriptPathIn(scriptDir, "*")} {
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			klog.Warningf("failed to list %s: %v", pattern, err)
+			continue
+		}
+		cleanupFiles(paths...)
+	}
 }
 
 func cleanupFiles(paths ...string) {
@@ -7,6 +7,8 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+
+	"github.com/openshift-virtualization/wasp-agent/pkg/consts"
 )
 
 var _ = Describe("OCI hook lifecycle", func() {
@@ -115,5 +117,24 @@ var _ = Describe("OCI hook lifecycle", func() {
 
 			Expect(scriptFile).ToNot(BeAnExistingFile())
 		})
+
+		It("should remove the hooks of all the agent pods", func() {
+			otherFile := filepath.Join(tmpDir, "other-hook.json")
+			Expect(os.WriteFile(otherFile, []byte("{}"), 0644)).To(Succeed())
+			var hookFiles []string
+			for _, suffix := range []string{"wasp-agent-abc12", "wasp-agent-def34"} {
+				hookFiles = append(hookFiles, consts.HookScriptPathIn(tmpDir, suffix), consts.HookConfigPathIn(tmpDir, suffix))
+			}
+			for _, hookFile := range hookFiles {
+				Expect(os.WriteFile(hookFile, []byte("{}"), 0644)).To(Succeed())
+			}
+
+			cleanupAllOCIHooks(tmpDir, tmpDir)
+
+			for _, hookFile := range hookFiles {
+				Expect(hookFile).ToNot(BeAnExistingFile())
+			}
+			Expect(otherFile).To(BeAnExistingFile())
+		})
 	})
 })
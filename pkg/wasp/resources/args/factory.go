@@ -6,6 +6,7 @@ type FactoryArgs struct {
 	WaspImage              string `required:"true" split_words:"true"`
 	DeployClusterResources string `required:"true" split_words:"true"`
 	DeployPrometheusRule   string `required:"true" split_words:"true"`
+	DeployServiceMonitor   string `required:"true" split_words:"true"`
 	Verbosity              string `required:"true"`
 	PullPolicy             string `required:"true" split_words:"true"`
 	Namespace              string
@@ -0,0 +1,133 @@
+package operator
+
+import (
+	"github.com/openshift-virtualization/wasp-agent/pkg/apis/core"
+	waspv1alpha1 "github.com/openshift-virtualization/wasp-agent/pkg/apis/core/v1alpha1"
+	utils2 "github.com/openshift-virtualization/wasp-agent/pkg/util"
+
+	extv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func createCRDs(_ *FactoryArgs) []client.Object {
+	return []client.Object{
+		createSwapPolicyCRD(),
+	}
+}
+
+func createSwapPolicyCRD() *extv1.CustomResourceDefinition {
+	return &extv1.CustomResourceDefinition{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: "apiextensions.k8s.io/v1",
+			Kind:       "CustomResourceDefinition",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "swappolicies." + core.GroupName,
+			Labels: map[string]string{utils2.WaspLabel: ""},
+		},
+		Spec: extv1.CustomResourceDefinitionSpec{
+			Group: core.GroupName,
+			Names: extv1.CustomResourceDefinitionNames{
+				Kind:     "SwapPolicy",
+				ListKind: "SwapPolicyList",
+				Plural:   "swappolicies",
+				Singular: "swappolicy",
+			},
+			Scope: extv1.ClusterScoped,
+			Versions: []extv1.CustomResourceDefinitionVersion{
+				{
+					Name:    waspv1alpha1.SchemeGroupVersion.Version,
+					Served:  true,
+					Storage: true,
+					AdditionalPrinterColumns: []extv1.CustomResourceColumnDefinition{
+						{Name: "Strategy", Type: "string", JSONPath: ".spec.strategy"},
+						{Name: "Priority", Type: "integer", JSONPath: ".spec.priority"},
+						{Name: "Age", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+					},
+					Schema: &extv1.CustomResourceValidation{
+						OpenAPIV3Schema: &extv1.JSONSchemaProps{
+							Description: "SwapPolicy configures how swap is granted to the pods it selects",
+							Type:        "object",
+							Properties: map[string]extv1.JSONSchemaProps{
+								"apiVersion": {Type: "string"},
+								"kind":       {Type: "string"},
+								"metadata":   {Type: "object"},
+								"spec": {
+									Type: "object",
+									Properties: map[string]extv1.JSONSchemaProps{
+										"namespaceSelector": labelSelectorSchema("NamespaceSelector selects the namespaces of the pods the policy applies to. An empty or missing selector matches all namespaces."),
+										"podSelector":       labelSelectorSchema("PodSelector selects the pods the policy applies to. An empty or missing selector matches all pods."),
+										"strategy": {
+											Description: "Strategy is the swap allocation policy for burstable containers, defaults to the policy configured on the agent",
+											Type:        "string",
+											Enum: []extv1.JSON{
+												{Raw: []byte(`"proportional-to-request"`)},
+												{Raw: []byte(`"proportional-to-limit-minus-request"`)},
+												{Raw: []byte(`"fixed-cap"`)},
+												{Raw: []byte(`"unlimited-for-burstable"`)},
+											},
+										},
+										"fixedCap":            quantitySchema("FixedCap is the swap granted to every burstable container by the fixed-cap strategy"),
+										"maxSwap":             quantitySchema("MaxSwap caps the swap of every container selected by the policy"),
+										"excludeCriticalPods": {Description: "ExcludeCriticalPods prevents critical pods from getting swap, defaults to true", Type: "boolean"},
+										"priority":            {Description: "Priority decides which policy applies when several policies select the same pod, the highest priority wins and ties are broken by name", Type: "integer", Format: "int32"},
+									},
+								},
+							},
+							Required: []string{"spec"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func quantitySchema(description string) extv1.JSONSchemaProps {
+	return extv1.JSONSchemaProps{
+		Description: description,
+		AnyOf: []extv1.JSONSchemaProps{
+			{Type: "integer"},
+			{Type: "string"},
+		},
+		Pattern:      `^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$`,
+		XIntOrString: true,
+	}
+}
+
+func labelSelectorSchema(description string) extv1.JSONSchemaProps {
+	return extv1.JSONSchemaProps{
+		Description: description,
+		Type:        "object",
+		Properties: map[string]extv1.JSONSchemaProps{
+			"matchLabels": {
+				Type: "object",
+				AdditionalProperties: &extv1.JSONSchemaPropsOrBool{
+					Schema: &extv1.JSONSchemaProps{Type: "string"},
+				},
+			},
+			"matchExpressions": {
+				Type: "array",
+				Items: &extv1.JSONSchemaPropsOrArray{
+					Schema: &extv1.JSONSchemaProps{
+						Type:     "object",
+						Required: []string{"key", "operator"},
+						Properties: map[string]extv1.JSONSchemaProps{
+							"key":      {Type: "string"},
+							"operator": {Type: "string"},
+							"values": {
+								Type: "array",
+								Items: &extv1.JSONSchemaPropsOrArray{
+									Schema: &extv1.JSONSchemaProps{Type: "string"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		XMapType: pointer.String("atomic"),
+	}
+}
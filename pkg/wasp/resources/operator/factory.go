@@ -42,11 +42,13 @@ func CreateOperatorResourceGroup(group string, args *FactoryArgs) ([]client.Obje
 }
 
 var waspFactoryFunctions = map[string]factoryFunc{
+	"wasp-crds":         createCRDs,
 	"wasp-cluster-rbac": createClusterRBAC,
 	"wasp-rbac":         createNamespacedRBAC,
 	"wasp-daemonset":    createDaemonSet,
 	"wasp-prom-rule":    createPrometheusRule,
-	"everything":        aggregateFactoryFunc(createClusterRBAC, createNamespacedRBAC, createDaemonSet, createPrometheusRule),
+	"wasp-monitoring":   createServiceMonitor,
+	"everything":        aggregateFactoryFunc(createCRDs, createClusterRBAC, createNamespacedRBAC, createDaemonSet, createPrometheusRule, createServiceMonitor),
 }
 
 // ClusterServiceVersionData - Data arguments used to create wasp's CSV manifest
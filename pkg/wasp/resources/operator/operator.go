@@ -3,10 +3,13 @@ package operator
 import (
 	"fmt"
 
+	"github.com/openshift-virtualization/wasp-agent/pkg/apis/core"
 	"github.com/openshift-virtualization/wasp-agent/pkg/monitoring/rules"
 	utils2 "github.com/openshift-virtualization/wasp-agent/pkg/util"
+	node_readiness "github.com/openshift-virtualization/wasp-agent/pkg/wasp/node-readiness"
 
 	secv1 "github.com/openshift/api/security/v1"
+	promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
@@ -14,14 +17,20 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/utils/pointer"
+	kubevirtv1 "kubevirt.io/api/core/v1"
 	"kubevirt.io/controller-lifecycle-operator-sdk/pkg/sdk/resources"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
-	roleName        = "wasp"
-	clusterRoleName = roleName + "-cluster"
-	promRuleName    = "wasp-rules"
+	roleName             = "wasp"
+	clusterRoleName      = roleName + "-cluster"
+	promRuleName         = "wasp-rules"
+	metricsName          = "wasp-agent-metrics"
+	metricsPortName      = "metrics"
+	metricsPort          = 8080
+	agentConfigName      = "wasp-agent-config"
+	agentConfigMountPath = "/etc/wasp-agent"
 )
 
 func getClusterPolicyRules() []rbacv1.PolicyRule {
@@ -39,8 +48,81 @@ func getClusterPolicyRules() []rbacv1.PolicyRule {
 				"list",
 			},
 		},
+		{
+			APIGroups: []string{
+				"",
+			},
+			Resources: []string{
+				"pods/eviction",
+			},
+			Verbs: []string{
+				"create",
+			},
+		},
+		{
+			APIGroups: []string{
+				"",
+			},
+			Resources: []string{
+				"namespaces",
+			},
+			Verbs: []string{
+				"watch",
+				"list",
+			},
+		},
+		{
+			APIGroups: []string{
+				"",
+			},
+			Resources: []string{
+				"nodes",
+				"nodes/status",
+			},
+			Verbs: []string{
+				"get",
+				"update",
+			},
+		},
+		{
+			APIGroups: []string{
+				core.GroupName,
+			},
+			Resources: []string{
+				"swappolicies",
+			},
+			Verbs: []string{
+				"get",
+				"watch",
+				"list",
+			},
+		},
+		{
+			APIGroups: []string{
+				kubevirtv1.GroupVersion.Group,
+			},
+			Resources: []string{
+				"virtualmachineinstances",
+			},
+			Verbs: []string{
+				"watch",
+				"list",
+			},
+		},
+		{
+			APIGroups: []string{
+				"",
+			},
+			Resources: []string{
+				"events",
+			},
+			Verbs: []string{
+				"create",
+				"patch",
+				"update",
+			},
+		},
 	}
-	rules = append(rules)
 	return rules
 }
 
@@ -78,6 +160,75 @@ func createPrometheusRule(args *FactoryArgs) []client.Object {
 	return nil
 }
 
+func createServiceMonitor(args *FactoryArgs) []client.Object {
+	if args.NamespacedArgs.DeployServiceMonitor == "true" {
+		return []client.Object{
+			createMetricsService(args.NamespacedArgs.Namespace),
+			createWaspServiceMonitor(args.NamespacedArgs.Namespace),
+		}
+	}
+
+	return nil
+}
+
+func createMetricsService(namespace string) *corev1.Service {
+	return &corev1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      metricsName,
+			Namespace: namespace,
+			Labels:    resources.WithLabels(map[string]string{"name": metricsName}, utils2.DaemonSetLabels),
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector: map[string]string{
+				"name": "wasp",
+			},
+			Ports: []corev1.ServicePort{
+				{
+					Name:       metricsPortName,
+					Port:       metricsPort,
+					TargetPort: intstr.FromString(metricsPortName),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+		},
+	}
+}
+
+func createWaspServiceMonitor(namespace string) *promv1.ServiceMonitor {
+	return &promv1.ServiceMonitor{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       promv1.ServiceMonitorsKind,
+			APIVersion: promv1.SchemeGroupVersion.String(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      metricsName,
+			Namespace: namespace,
+			Labels:    resources.WithLabels(map[string]string{"name": metricsName}, utils2.DaemonSetLabels),
+		},
+		Spec: promv1.ServiceMonitorSpec{
+			Selector: metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					"name": metricsName,
+				},
+			},
+			NamespaceSelector: promv1.NamespaceSelector{
+				MatchNames: []string{namespace},
+			},
+			Endpoints: []promv1.Endpoint{
+				{
+					Port:   metricsPortName,
+					Scheme: "http",
+				},
+			},
+		},
+	}
+}
+
 func createDaemonSet(args *FactoryArgs) []client.Object {
 	return []client.Object{
 		createWaspDaemonSet(args.NamespacedArgs.Namespace,
@@ -109,6 +260,7 @@ func createWaspDaemonSet(namespace, verbosity, waspImage, pullPolicy string) *ap
 		Name:            "wasp-agent",
 		Image:           waspImage,
 		ImagePullPolicy: corev1.PullPolicy(pullPolicy),
+		Args:            []string{"--config=" + agentConfigMountPath + "/config.yaml"},
 		Resources: corev1.ResourceRequirements{
 			Requests: corev1.ResourceList{
 				corev1.ResourceCPU:    resource.MustParse("100m"),
@@ -118,6 +270,13 @@ func createWaspDaemonSet(namespace, verbosity, waspImage, pullPolicy string) *ap
 		SecurityContext: &corev1.SecurityContext{
 			Privileged: boolPtr(true),
 		},
+		Ports: []corev1.ContainerPort{
+			{
+				Name:          metricsPortName,
+				ContainerPort: metricsPort,
+				Protocol:      corev1.ProtocolTCP,
+			},
+		},
 		VolumeMounts: []corev1.VolumeMount{
 			{
 				Name:      "host",
@@ -127,6 +286,11 @@ func createWaspDaemonSet(namespace, verbosity, waspImage, pullPolicy string) *ap
 				Name:      "rootfs",
 				MountPath: "/rootfs",
 			},
+			{
+				Name:      agentConfigName,
+				MountPath: agentConfigMountPath,
+				ReadOnly:  true,
+			},
 		},
 	}
 	container.Env = createDaemonSetEnvVar(verbosity)
@@ -164,6 +328,14 @@ func createWaspDaemonSet(namespace, verbosity, waspImage, pullPolicy string) *ap
 					HostUsers:                     boolPtr(true),
 					TerminationGracePeriodSeconds: int64Ptr(5),
 					Containers:                    []corev1.Container{container},
+					Tolerations: []corev1.Toleration{
+						{
+							// the agent removes the startup taint once it manages the swap of the pods on the node
+							Key:      node_readiness.StartupTaintKey,
+							Operator: corev1.TolerationOpExists,
+							Effect:   corev1.TaintEffectNoSchedule,
+						},
+					},
 					Volumes: []corev1.Volume{
 						{
 							Name: "host",
@@ -181,6 +353,16 @@ func createWaspDaemonSet(namespace, verbosity, waspImage, pullPolicy string) *ap
 								},
 							},
 						},
+						{
+							// the agent runs with the flag defaults until the ConfigMap is created
+							Name: agentConfigName,
+							VolumeSource: corev1.VolumeSource{
+								ConfigMap: &corev1.ConfigMapVolumeSource{
+									LocalObjectReference: corev1.LocalObjectReference{Name: agentConfigName},
+									Optional:             boolPtr(true),
+								},
+							},
+						},
 					},
 					PriorityClassName: "system-node-critical",
 				},
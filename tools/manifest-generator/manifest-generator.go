@@ -52,6 +52,7 @@ var (
 	genManifestsPath       = flag.String("generated-manifests-path", "", "")
 	deployClusterResources = flag.String("deploy-cluster-resources", "", "")
 	deployPrometheusRule   = flag.String("deploy-prometheus-rule", "", "")
+	deployServiceMonitor   = flag.String("deploy-service-monitor", "", "")
 	operatorImage          = flag.String("operator-image", "", "")
 	verbosity              = flag.String("verbosity", "1", "")
 	pullPolicy             = flag.String("pull-policy", "", "")
@@ -165,6 +166,7 @@ func getOperatorResources(resourceGroup string) ([]client.Object, error) {
 			OperatorVersion:        *operatorVersion,
 			DeployClusterResources: *deployClusterResources,
 			DeployPrometheusRule:   *deployPrometheusRule,
+			DeployServiceMonitor:   *deployServiceMonitor,
 			PullPolicy:             *pullPolicy,
 			Namespace:              *namespace,
 		},